@@ -0,0 +1,197 @@
+package comet
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ramoncl001/go-comet/logs"
+)
+
+type AccessLogFormat int
+
+const (
+	// AccessLogStructured logs every request as a record of logs.Logger
+	AccessLogStructured AccessLogFormat = iota
+	// AccessLogCommon writes a line in the Common Log Format
+	AccessLogCommon
+	// AccessLogCombined writes a line in the Combined Log Format,
+	// adding the referer and user agent to the common one
+	AccessLogCombined
+)
+
+// AccessLogConfig configures the AccessLog middleware
+type AccessLogConfig struct {
+	Format AccessLogFormat
+	// Logger receiving structured records, the request logger by default
+	Logger logs.Logger
+	// Output receiving Common and Combined Log Format lines, os.Stdout by default
+	Output io.Writer
+	// SampleRate is the fraction of successful requests logged, between 0
+	// and 1. Every request is logged when zero, and server errors always are.
+	SampleRate float64
+	// SkipPaths are paths or route patterns never logged, like health checks
+	SkipPaths []string
+	// Skip reports whether a request should not be logged
+	Skip func(r *Request) bool
+	// Headers are request headers added to structured records, their
+	// values removed as configured in the logs redaction rules
+	Headers []string
+}
+
+// AccessLog returns a middleware logging every request once its response has
+// been written, with its method, route pattern, path, status, latency, bytes
+// in and out, remote address and user agent
+func AccessLog(config AccessLogConfig) Middleware {
+	if config.Output == nil {
+		config.Output = os.Stdout
+	}
+
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = true
+	}
+
+	// lines are written whole, as Output may be shared between goroutines
+	var mu sync.Mutex
+
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			if skip[r.Url.Path] || skip[r.Route()] || config.Skip != nil && config.Skip(r) {
+				return next(r)
+			}
+
+			start := time.Now()
+
+			// the principal is usually authenticated by a later middleware
+			ctx, holder := withPrincipalHolder(r.Context())
+			r = r.WithContext(ctx)
+
+			entry := func(status, size int) {
+				if status < 500 && config.SampleRate > 0 && rand.Float64() >= config.SampleRate {
+					return
+				}
+
+				latency := time.Since(start)
+				switch config.Format {
+				case AccessLogCommon, AccessLogCombined:
+					line := accessLogLine(r, holder.principal, start, status, size, config.Format == AccessLogCombined)
+					mu.Lock()
+					io.WriteString(config.Output, line)
+					mu.Unlock()
+				default:
+					logAccess(config, r, holder.principal, status, size, latency)
+				}
+			}
+
+			if r.state == nil {
+				// outside of a router the response size is unknown
+				response := next(r)
+				entry(response.Status, -1)
+				return response
+			}
+
+			r.state.afterWrite = append(r.state.afterWrite, entry)
+			return next(r)
+		}
+	}
+}
+
+// logAccess logs a structured record. The principal, if authenticated after
+// AccessLog ran, is added as the request logger does not carry it yet.
+func logAccess(config AccessLogConfig, r *Request, principal *Principal, status, size int, latency time.Duration) {
+	logger := config.Logger
+	if logger == nil {
+		logger = logs.FromContext(r.Context())
+	}
+
+	args := []interface{}{
+		"method", r.Method,
+		"route", r.Route(),
+		"path", r.Url.Path,
+		"status", status,
+		"latency_ms", float64(latency.Microseconds()) / 1000,
+		"bytes_in", len(r.Body),
+		"bytes_out", size,
+		"remote_addr", r.RemoteAddress,
+		"user_agent", r.UserAgent,
+	}
+
+	if principal != nil && User(r) == nil {
+		args = append(args, "principal", principal.Name)
+	}
+
+	if len(config.Headers) > 0 {
+		headers := make(http.Header, len(config.Headers))
+		for _, name := range config.Headers {
+			if values := http.Header(r.Headers).Values(name); len(values) > 0 {
+				headers[http.CanonicalHeaderKey(name)] = values
+			}
+		}
+		args = append(args, "headers", logs.RedactHeaders(headers))
+	}
+
+	switch {
+	case status >= 500:
+		logger.Error("request", args...)
+	case status >= 400:
+		logger.Warn("request", args...)
+	default:
+		logger.Info("request", args...)
+	}
+}
+
+// accessLogLine formats a request in the Common or Combined Log Format
+//
+//	127.0.0.1 - alice [10/Oct/2000:13:55:36 -0700] "GET /orders HTTP/1.1" 200 2326 "-" "curl/8.0"
+func accessLogLine(r *Request, principal *Principal, start time.Time, status, size int, combined bool) string {
+	host := r.RemoteAddress
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if principal == nil {
+		principal = User(r)
+	}
+
+	user := "-"
+	if principal != nil && principal.Name != "" {
+		user = principal.Name
+	}
+
+	proto := "HTTP/1.1"
+	if r.state != nil && r.state.proto != "" {
+		proto = r.state.proto
+	}
+
+	bytes := "-"
+	if size > 0 {
+		bytes = strconv.Itoa(size)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s [%s] \"%s %s %s\" %d %s",
+		orDash(host), user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method, logs.RedactURI(r.Url.RequestURI()), proto, status, bytes)
+
+	if combined {
+		fmt.Fprintf(&b, " %q %q", orDash(logs.RedactURI(r.Header("Referer"))), orDash(r.UserAgent))
+	}
+
+	b.WriteString("\n")
+	return b.String()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
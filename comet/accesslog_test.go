@@ -0,0 +1,26 @@
+package comet
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogPrincipalAuthenticatedLater(t *testing.T) {
+	var output bytes.Buffer
+
+	router := NewDefaultRouter()
+	router.Use(AccessLog(AccessLogConfig{Format: AccessLogCommon, Output: &output}))
+	router.Use(Authentication(stubScheme{principal: &Principal{Name: "alice"}}))
+	router.MapGet("/orders", func(*Request) Response {
+		return Ok("orders")
+	})
+
+	w := httptest.NewRecorder()
+	router.handler().ServeHTTP(w, httptest.NewRequest("GET", "/orders", nil))
+
+	if line := output.String(); !strings.Contains(line, " - alice [") {
+		t.Fatalf("expected the principal in the access log, got %q", line)
+	}
+}
@@ -0,0 +1,158 @@
+package comet
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+// APIKeyStore looks up the principal owning an API key. Implementations
+// return ErrInvalidCredentials when the key is unknown.
+type APIKeyStore interface {
+	LookupAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// APIKeyScheme authenticates requests carrying an API key in a header
+// or, optionally, in a query parameter
+type APIKeyScheme struct {
+	// Header holding the key, "X-API-Key" by default
+	Header string
+	// Query parameter holding the key, disabled when empty
+	Query string
+	// Store verifying the keys. When nil an APIKeyStore is resolved
+	// from the ioc container on every request.
+	Store APIKeyStore
+}
+
+func NewAPIKeyScheme(store APIKeyStore) *APIKeyScheme {
+	return &APIKeyScheme{
+		Header: "X-API-Key",
+		Store:  store,
+	}
+}
+
+func (s *APIKeyScheme) Name() string {
+	return "ApiKey"
+}
+
+func (s *APIKeyScheme) Challenge() string {
+	return ""
+}
+
+func (s *APIKeyScheme) Authenticate(r *Request) (*Principal, error) {
+	header := s.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+
+	key := r.Header(header)
+	if key == "" && s.Query != "" {
+		if values := r.QueryParams[s.Query]; len(values) > 0 {
+			key = values[0]
+		}
+	}
+
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	store := s.Store
+	if store == nil {
+		resolved, err := ioc.Resolve[APIKeyStore](r.Context())
+		if err != nil {
+			return nil, err
+		}
+		store = resolved
+	}
+
+	return store.LookupAPIKey(r.Context(), key)
+}
+
+type apiKeyEntry struct {
+	hash  []byte
+	name  string
+	roles []string
+}
+
+// MemoryAPIKeyStore keeps SHA-256 hashes of API keys in memory
+type MemoryAPIKeyStore struct {
+	mu      sync.RWMutex
+	entries []apiKeyEntry
+}
+
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{
+		entries: make([]apiKeyEntry, 0),
+	}
+}
+
+// NewFileAPIKeyStore loads API keys from a JSON file with the format
+//
+//	[{"name": "billing", "hash": "<HashAPIKey output>", "roles": ["invoices"]}]
+func NewFileAPIKeyStore(path string) (*MemoryAPIKeyStore, error) {
+	entries, err := readCredentialFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	store := NewMemoryAPIKeyStore()
+	for _, entry := range entries {
+		if err := store.AddHashed(entry.Hash, entry.Name, entry.Roles...); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// Add registers an API key for the named principal. Only its hash is kept.
+func (s *MemoryAPIKeyStore) Add(key, name string, roles ...string) {
+	_ = s.AddHashed(HashAPIKey(key), name, roles...)
+}
+
+// AddHashed registers an API key by its HashAPIKey output
+func (s *MemoryAPIKeyStore) AddHashed(hash, name string, roles ...string) error {
+	decoded, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, apiKeyEntry{
+		hash:  decoded,
+		name:  name,
+		roles: roles,
+	})
+
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) LookupAPIKey(_ context.Context, key string) (*Principal, error) {
+	hash, _ := hex.DecodeString(HashAPIKey(key))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// every entry is compared so the lookup time does not depend
+	// on the position of the matching key
+	var found *apiKeyEntry
+	for i := range s.entries {
+		if subtle.ConstantTimeCompare(s.entries[i].hash, hash) == 1 {
+			found = &s.entries[i]
+		}
+	}
+
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		Name:  found.name,
+		Roles: append([]string(nil), found.roles...),
+	}, nil
+}
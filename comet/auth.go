@@ -0,0 +1,186 @@
+package comet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ramoncl001/go-comet/logs"
+)
+
+var (
+	// ErrNoCredentials is returned by an AuthenticationScheme when the request
+	// carries no credentials for that scheme, so the next scheme can be tried
+	ErrNoCredentials = errors.New("no credentials provided")
+
+	// ErrInvalidCredentials is returned by an AuthenticationScheme when the
+	// request carries credentials that could not be verified
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the identity authenticated for the current request
+type Principal struct {
+	Name   string
+	Roles  []string
+	Scheme string
+}
+
+// HasRole reports whether the principal has been granted the given role
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AuthenticationScheme extracts and verifies the credentials of a request.
+// Challenge returns the value sent in the WWW-Authenticate header when
+// the request is rejected, or an empty string to send none.
+type AuthenticationScheme interface {
+	Name() string
+	Authenticate(r *Request) (*Principal, error)
+	Challenge() string
+}
+
+type principalKey struct{}
+
+// Authentication returns a middleware that authenticates every request with
+// the first scheme that finds credentials in it. Requests without credentials
+// continue anonymously, so routes must be protected with policies such as
+// RequireAuthenticated or RequireRole.
+func Authentication(schemes ...AuthenticationScheme) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			for _, scheme := range schemes {
+				principal, err := scheme.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+
+				if errors.Is(err, ErrInvalidCredentials) {
+					return challenge(Unauthorized(), schemes)
+				}
+
+				if err != nil {
+					logs.FromContext(r.Context()).Error("authentication failed", "scheme", scheme.Name(), "error", err)
+					return Error("authentication failed")
+				}
+
+				// schemes returning no principal did not authenticate the request
+				if principal == nil {
+					continue
+				}
+
+				if principal.Scheme == "" {
+					principal.Scheme = scheme.Name()
+				}
+
+				r = r.WithContext(WithPrincipal(r.Context(), principal))
+				break
+			}
+
+			response := next(r)
+			if response.Status == 401 {
+				response = challenge(response, schemes)
+			}
+
+			return response
+		}
+	}
+}
+
+// WithPrincipal returns a copy of ctx carrying the given principal,
+// which is also added to the records of its loggers
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	if principal != nil {
+		ctx = logs.WithFields(ctx, "principal", principal.Name)
+	}
+
+	if holder, ok := ctx.Value(principalHolderKey{}).(*principalHolder); ok {
+		holder.principal = principal
+	}
+
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+type principalHolderKey struct{}
+
+// principalHolder receives the principal authenticated further down the
+// middleware chain, for middlewares like AccessLog that run before
+// Authentication but report the principal once the response is written
+type principalHolder struct {
+	principal *Principal
+}
+
+// withPrincipalHolder returns a copy of ctx whose next WithPrincipal
+// calls are recorded in the returned holder
+func withPrincipalHolder(ctx context.Context) (context.Context, *principalHolder) {
+	holder := &principalHolder{}
+	return context.WithValue(ctx, principalHolderKey{}, holder), holder
+}
+
+// PrincipalFromContext returns the principal authenticated for ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// User returns the principal authenticated for the request, or nil
+// when the request is anonymous
+func User(r *Request) *Principal {
+	principal, _ := PrincipalFromContext(r.Context())
+	return principal
+}
+
+// RequireAuthenticated is an AuthorizerFunction rejecting anonymous requests.
+//
+//	comet.Authorize(comet.RequireAuthenticated, nil)
+func RequireAuthenticated(next RequestHandler, _ interface{}) RequestHandler {
+	return func(r *Request) Response {
+		if User(r) == nil {
+			return Unauthorized()
+		}
+		return next(r)
+	}
+}
+
+// RequireRole is an AuthorizerFunction rejecting requests whose principal
+// has not been granted the role given as policy value.
+//
+//	comet.Authorize(comet.RequireRole, "admin")
+func RequireRole(next RequestHandler, role interface{}) RequestHandler {
+	name := fmt.Sprint(role)
+	return func(r *Request) Response {
+		principal := User(r)
+		if principal == nil {
+			return Unauthorized()
+		}
+
+		if !principal.HasRole(name) {
+			return Forbidden()
+		}
+
+		return next(r)
+	}
+}
+
+func challenge(response Response, schemes []AuthenticationScheme) Response {
+	if response.Headers.Get("WWW-Authenticate") != "" {
+		return response
+	}
+
+	for _, scheme := range schemes {
+		if value := scheme.Challenge(); value != "" {
+			response = response.WithHeader("WWW-Authenticate", value)
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,53 @@
+package comet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type stubScheme struct {
+	principal *Principal
+	err       error
+}
+
+func (s stubScheme) Name() string      { return "stub" }
+func (s stubScheme) Challenge() string { return "" }
+
+func (s stubScheme) Authenticate(*Request) (*Principal, error) {
+	return s.principal, s.err
+}
+
+func TestAuthenticationNilPrincipal(t *testing.T) {
+	handler := Authentication(stubScheme{}, stubScheme{principal: &Principal{Name: "alice"}})(func(r *Request) Response {
+		return Ok(User(r))
+	})
+
+	response := handler(&Request{Headers: map[string][]string{}})
+	if user, _ := response.Data.(*Principal); user == nil || user.Name != "alice" || user.Scheme != "stub" {
+		t.Fatalf("expected the next scheme to authenticate the request, got %v", response.Data)
+	}
+}
+
+func TestAuthenticationError(t *testing.T) {
+	handler := Authentication(stubScheme{err: errors.New("store unavailable")})(func(r *Request) Response {
+		return Ok("reached")
+	})
+
+	if response := handler(&Request{Headers: map[string][]string{}}); response.Status != 500 {
+		t.Fatalf("expected 500, got %d", response.Status)
+	}
+}
+
+func TestFileCredentialStoreRejectsMalformedHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	content := `[{"username": "legacy", "hash": "md5$deadbeef"}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileCredentialStore(path); !errors.Is(err, errInvalidPasswordHash) {
+		t.Fatalf("expected errInvalidPasswordHash, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package comet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+// CredentialStore verifies username and password pairs. Implementations
+// return ErrInvalidCredentials when they do not match.
+type CredentialStore interface {
+	VerifyCredentials(ctx context.Context, username, password string) (*Principal, error)
+}
+
+// BasicScheme authenticates requests using HTTP Basic authentication
+type BasicScheme struct {
+	Realm string
+	// Store verifying the credentials. When nil a CredentialStore is
+	// resolved from the ioc container on every request.
+	Store CredentialStore
+}
+
+func NewBasicScheme(realm string, store CredentialStore) *BasicScheme {
+	return &BasicScheme{
+		Realm: realm,
+		Store: store,
+	}
+}
+
+func (s *BasicScheme) Name() string {
+	return "Basic"
+}
+
+func (s *BasicScheme) Challenge() string {
+	realm := s.Realm
+	if realm == "" {
+		realm = "comet"
+	}
+	return fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)
+}
+
+func (s *BasicScheme) Authenticate(r *Request) (*Principal, error) {
+	request := &http.Request{Header: http.Header(r.Headers)}
+	username, password, ok := request.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	store := s.Store
+	if store == nil {
+		resolved, err := ioc.Resolve[CredentialStore](r.Context())
+		if err != nil {
+			return nil, err
+		}
+		store = resolved
+	}
+
+	return store.VerifyCredentials(r.Context(), username, password)
+}
+
+type userEntry struct {
+	hash  string
+	roles []string
+}
+
+// MemoryCredentialStore keeps PBKDF2 password hashes in memory
+type MemoryCredentialStore struct {
+	mu    sync.RWMutex
+	users map[string]userEntry
+	dummy string
+}
+
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	dummy, _ := HashPassword("")
+	return &MemoryCredentialStore{
+		users: make(map[string]userEntry),
+		dummy: dummy,
+	}
+}
+
+// NewFileCredentialStore loads users from a JSON file with the format
+//
+//	[{"username": "legacy", "hash": "<HashPassword output>", "roles": ["reports"]}]
+func NewFileCredentialStore(path string) (*MemoryCredentialStore, error) {
+	entries, err := readCredentialFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	store := NewMemoryCredentialStore()
+	for _, entry := range entries {
+		// malformed hashes are reported now instead of failing every login
+		if _, _, _, err := parsePasswordHash(entry.Hash); err != nil {
+			return nil, fmt.Errorf("user %q in credentials file %s: %w", entry.Username, path, err)
+		}
+		store.AddHashed(entry.Username, entry.Hash, entry.Roles...)
+	}
+
+	return store, nil
+}
+
+// Add registers a user, hashing its password
+func (s *MemoryCredentialStore) Add(username, password string, roles ...string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.AddHashed(username, hash, roles...)
+	return nil
+}
+
+// AddHashed registers a user by its HashPassword output
+func (s *MemoryCredentialStore) AddHashed(username, hash string, roles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[username] = userEntry{
+		hash:  hash,
+		roles: roles,
+	}
+}
+
+func (s *MemoryCredentialStore) VerifyCredentials(_ context.Context, username, password string) (*Principal, error) {
+	s.mu.RLock()
+	user, ok := s.users[username]
+	s.mu.RUnlock()
+
+	// unknown users are verified against a dummy hash so they take
+	// as long to reject as a wrong password
+	hash := user.hash
+	if !ok {
+		hash = s.dummy
+	}
+
+	valid, err := verifyPassword(password, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok || !valid {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{
+		Name:  username,
+		Roles: append([]string(nil), user.roles...),
+	}, nil
+}
@@ -16,10 +16,10 @@ func (r requestMethod) method() string {
 }
 
 const (
-	list   requestMethod = "LIST"
-	get    requestMethod = "GET"
-	post   requestMethod = "POST"
-	put    requestMethod = "PUT"
-	delete requestMethod = "DELETE"
-	patch  requestMethod = "PATCH"
+	list  requestMethod = "LIST"
+	get   requestMethod = "GET"
+	post  requestMethod = "POST"
+	put   requestMethod = "PUT"
+	del   requestMethod = "DELETE"
+	patch requestMethod = "PATCH"
 )
@@ -0,0 +1,147 @@
+package comet
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	passwordHashPrefix     = "pbkdf2-sha256"
+	passwordHashIterations = 210000
+	passwordSaltLength     = 16
+)
+
+var errInvalidPasswordHash = errors.New("invalid password hash")
+
+// credentialEntry is the file representation of an API key or user, as read
+// by NewFileAPIKeyStore and NewFileCredentialStore. Secrets are never stored
+// in clear: Hash holds the output of HashAPIKey or HashPassword.
+type credentialEntry struct {
+	Name     string   `json:"name"`
+	Username string   `json:"username"`
+	Hash     string   `json:"hash"`
+	Roles    []string `json:"roles"`
+}
+
+// HashAPIKey returns the hex encoded SHA-256 digest under which an API key
+// is stored. API keys are expected to be long random strings, so a fast
+// digest is enough to keep them unreadable at rest.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword returns a salted PBKDF2-SHA256 hash of the password,
+// suitable for MemoryCredentialStore.AddHashed and credential files
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := pbkdf2([]byte(password), salt, passwordHashIterations, sha256.Size)
+	return fmt.Sprintf("%s$%d$%s$%s",
+		passwordHashPrefix,
+		passwordHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyPassword compares a password against a HashPassword output
+// in constant time
+func verifyPassword(password, hash string) (bool, error) {
+	iterations, salt, expected, err := parsePasswordHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key := pbkdf2([]byte(password), salt, iterations, len(expected))
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+// parsePasswordHash splits a HashPassword output into its iterations,
+// salt and derived key
+func parsePasswordHash(hash string) (int, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != passwordHashPrefix {
+		return 0, nil, nil, errInvalidPasswordHash
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return 0, nil, nil, errInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(salt) == 0 {
+		return 0, nil, nil, errInvalidPasswordHash
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return 0, nil, nil, errInvalidPasswordHash
+	}
+
+	return iterations, salt, expected, nil
+}
+
+// pbkdf2 implements PBKDF2 (RFC 8018) with HMAC-SHA256
+func pbkdf2(password, salt []byte, iterations, keyLength int) []byte {
+	prf := hmac.New(sha256.New, password)
+	blocks := (keyLength + sha256.Size - 1) / sha256.Size
+
+	result := make([]byte, 0, blocks*sha256.Size)
+	counter := make([]byte, 4)
+	u := make([]byte, sha256.Size)
+
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter, uint32(block))
+
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter)
+		u = prf.Sum(u[:0])
+
+		t := make([]byte, len(u))
+		copy(t, u)
+
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		result = append(result, t...)
+	}
+
+	return result[:keyLength]
+}
+
+func readCredentialFile(path string) ([]credentialEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]credentialEntry, 0)
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("parsing credentials file %s: %w", path, err)
+	}
+
+	return entries, nil
+}
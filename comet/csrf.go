@@ -0,0 +1,243 @@
+package comet
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+const csrfTokenLength = 32
+
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit keeps the token in a cookie readable by scripts
+	// and expects the same token back in a header or form field
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer keeps the token in the session, so it requires
+	// the Sessions middleware to run before CSRF
+	CSRFSynchronizer
+)
+
+// CSRFConfig configures the CSRF middleware
+type CSRFConfig struct {
+	Mode CSRFMode
+	// HeaderName carrying the token, "X-CSRF-Token" by default
+	HeaderName string
+	// FieldName carrying the token in form bodies, "csrf_token" by default
+	FieldName string
+	// CookieName holding the token in double submit mode, "csrf_token" by default
+	CookieName string
+	// SessionKey holding the token in synchronizer mode, "_csrf_token" by default
+	SessionKey string
+
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+type csrfKey struct{}
+
+type csrfExempt struct{}
+
+// CSRFExempt returns a policy disabling CSRF validation for the
+// routes it is applied to, through group or controller policies
+//
+//	group.UsePolicy(comet.CSRFExempt())
+func CSRFExempt() Policy {
+	return Authorize(func(next RequestHandler, _ interface{}) RequestHandler {
+		return next
+	}, csrfExempt{})
+}
+
+// CSRFToken returns a masked CSRF token for the request, to be embedded in
+// templates or returned to single page applications. Every call returns a
+// different value that validates against the same token.
+func CSRFToken(r *Request) string {
+	token, ok := r.Context().Value(csrfKey{}).(func() []byte)
+	if !ok {
+		return ""
+	}
+	return maskCSRFToken(token())
+}
+
+// CSRF returns a middleware rejecting unsafe requests that do not carry
+// a valid CSRF token. GET, HEAD, OPTIONS and TRACE requests are never
+// validated, nor routes with the CSRFExempt policy.
+func CSRF(config CSRFConfig) Middleware {
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+
+	if config.FieldName == "" {
+		config.FieldName = "csrf_token"
+	}
+
+	if config.CookieName == "" {
+		config.CookieName = "csrf_token"
+	}
+
+	if config.SessionKey == "" {
+		config.SessionKey = "_csrf_token"
+	}
+
+	if config.Path == "" {
+		config.Path = "/"
+	}
+
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			var token []byte
+			var current func() []byte
+			var cookie *http.Cookie
+
+			switch config.Mode {
+			case CSRFSynchronizer:
+				session := Session(r)
+				if session == nil {
+					return Error("csrf protection requires sessions")
+				}
+
+				// the token is rotated when the session is regenerated on
+				// login, so tokens issued before are not valid after it
+				session.rotateOnRegenerate(config.SessionKey)
+				current = func() []byte {
+					token := decodeCSRFToken(session.GetString(config.SessionKey))
+					if token == nil {
+						token = newCSRFToken()
+						session.Set(config.SessionKey, base64.RawURLEncoding.EncodeToString(token))
+					}
+					return token
+				}
+				token = current()
+			default:
+				if c, err := r.Cookie(config.CookieName); err == nil {
+					token = decodeCSRFToken(c.Value)
+				}
+
+				if token == nil {
+					token = newCSRFToken()
+					cookie = &http.Cookie{
+						Name:     config.CookieName,
+						Value:    base64.RawURLEncoding.EncodeToString(token),
+						Path:     config.Path,
+						Domain:   config.Domain,
+						Secure:   config.Secure,
+						SameSite: config.SameSite,
+					}
+				}
+
+				current = func() []byte {
+					return token
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), csrfKey{}, current))
+
+			var response Response
+			if isSafeMethod(r.Method) || isCSRFExempt(r) || validCSRFToken(token, submittedCSRFToken(r, config)) {
+				response = next(r)
+			} else {
+				response = Forbidden()
+				response.Data = "invalid csrf token"
+			}
+
+			if cookie != nil {
+				response = response.WithCookie(cookie)
+			}
+
+			return response
+		}
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func isCSRFExempt(r *Request) bool {
+	match := r.route()
+	return match != nil && hasPolicy(match.Policies, csrfExempt{})
+}
+
+func submittedCSRFToken(r *Request, config CSRFConfig) string {
+	if token := r.Header(config.HeaderName); token != "" {
+		return token
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return ""
+	}
+
+	form, err := url.ParseQuery(string(r.Body))
+	if err != nil {
+		return ""
+	}
+
+	return form.Get(config.FieldName)
+}
+
+// validCSRFToken accepts both the raw token, as read from the cookie by
+// scripts, and the masked values returned by CSRFToken
+func validCSRFToken(token []byte, submitted string) bool {
+	value, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil {
+		return false
+	}
+
+	if len(value) == 2*csrfTokenLength {
+		value = unmaskCSRFToken(value)
+	}
+
+	return len(value) == csrfTokenLength && subtle.ConstantTimeCompare(value, token) == 1
+}
+
+func newCSRFToken() []byte {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		panic("comet: unable to generate csrf token: " + err.Error())
+	}
+	return token
+}
+
+func decodeCSRFToken(value string) []byte {
+	token, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(token) != csrfTokenLength {
+		return nil
+	}
+	return token
+}
+
+// maskCSRFToken XORs the token with a random pad so its value changes on
+// every response, preventing BREACH style compression attacks
+func maskCSRFToken(token []byte) string {
+	pad := newCSRFToken()
+	masked := make([]byte, 2*csrfTokenLength)
+	copy(masked, pad)
+	for i := range token {
+		masked[csrfTokenLength+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func unmaskCSRFToken(masked []byte) []byte {
+	token := make([]byte, csrfTokenLength)
+	for i := range token {
+		token[i] = masked[i] ^ masked[csrfTokenLength+i]
+	}
+	return token
+}
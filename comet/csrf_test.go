@@ -0,0 +1,260 @@
+package comet
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// csrfCookie returns the token cookie set by the double submit mode
+func csrfCookie(t *testing.T, handler RequestHandler) *http.Cookie {
+	t.Helper()
+
+	response := handler(&Request{Method: http.MethodGet, Headers: map[string][]string{}})
+	for _, cookie := range (&http.Response{Header: response.Headers}).Cookies() {
+		if cookie.Name == "csrf_token" {
+			return cookie
+		}
+	}
+
+	t.Fatal("expected a csrf_token cookie")
+	return nil
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	var masked string
+	handler := CSRF(CSRFConfig{})(func(r *Request) Response {
+		masked = CSRFToken(r)
+		return Ok("reached")
+	})
+
+	cookie := csrfCookie(t, handler)
+	if cookie.HttpOnly {
+		t.Fatal("expected the token cookie to be readable by scripts")
+	}
+
+	form := url.Values{"csrf_token": {cookie.Value}}.Encode()
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string][]string
+		body    string
+		status  int
+	}{
+		{name: "get", method: http.MethodGet, status: 200},
+		{name: "head", method: http.MethodHead, status: 200},
+		{name: "options", method: http.MethodOptions, status: 200},
+		{name: "trace", method: http.MethodTrace, status: 200},
+		{name: "post without token", method: http.MethodPost, status: 403},
+		{name: "delete without token", method: http.MethodDelete, status: 403},
+		{name: "raw header", method: http.MethodPost, headers: map[string][]string{"X-Csrf-Token": {cookie.Value}}, status: 200},
+		{name: "masked header", method: http.MethodPut, headers: map[string][]string{"X-Csrf-Token": {masked}}, status: 200},
+		{name: "wrong token", method: http.MethodPost, headers: map[string][]string{"X-Csrf-Token": {base64.RawURLEncoding.EncodeToString(newCSRFToken())}}, status: 403},
+		{name: "malformed token", method: http.MethodPost, headers: map[string][]string{"X-Csrf-Token": {"not a token"}}, status: 403},
+		{name: "short token", method: http.MethodPost, headers: map[string][]string{"X-Csrf-Token": {cookie.Value[:10]}}, status: 403},
+		{
+			name:    "form field",
+			method:  http.MethodPost,
+			headers: map[string][]string{"Content-Type": {"application/x-www-form-urlencoded; charset=utf-8"}},
+			body:    form,
+			status:  200,
+		},
+		{
+			name:    "form field in a json body",
+			method:  http.MethodPost,
+			headers: map[string][]string{"Content-Type": {"application/json"}},
+			body:    form,
+			status:  403,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			headers := map[string][]string{"Cookie": {cookie.Name + "=" + cookie.Value}}
+			for key, values := range test.headers {
+				headers[key] = values
+			}
+
+			response := handler(&Request{Method: test.method, Headers: headers, Body: []byte(test.body)})
+			if response.Status != test.status {
+				t.Fatalf("expected %d, got %d", test.status, response.Status)
+			}
+
+			if response.Headers.Get("Set-Cookie") != "" {
+				t.Fatal("expected the existing token cookie to be kept")
+			}
+		})
+	}
+}
+
+func TestCSRFDoubleSubmitWithoutCookie(t *testing.T) {
+	handler := CSRF(CSRFConfig{})(func(*Request) Response {
+		return Ok("reached")
+	})
+
+	// a token not matching any cookie is rejected, and a new cookie is set
+	token := base64.RawURLEncoding.EncodeToString(newCSRFToken())
+	response := handler(&Request{Method: http.MethodPost, Headers: map[string][]string{"X-Csrf-Token": {token}}})
+	if response.Status != 403 || !strings.Contains(response.Headers.Get("Set-Cookie"), "csrf_token=") {
+		t.Fatalf("expected 403 with a new token cookie, got %d %v", response.Status, response.Headers)
+	}
+}
+
+func TestCSRFMaskedTokensDiffer(t *testing.T) {
+	token := newCSRFToken()
+	first, second := maskCSRFToken(token), maskCSRFToken(token)
+	if first == second {
+		t.Fatal("expected every masked token to be different")
+	}
+
+	for _, masked := range []string{first, second} {
+		if !validCSRFToken(token, masked) {
+			t.Fatalf("expected %s to validate", masked)
+		}
+	}
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	var token string
+	client := &sessionClient{
+		config:      SessionConfig{Secret: testSessionSecret},
+		middlewares: []Middleware{CSRF(CSRFConfig{Mode: CSRFSynchronizer})},
+	}
+
+	client.send(&Request{Method: http.MethodGet}, func(r *Request) Response {
+		token = CSRFToken(r)
+		return Ok("")
+	})
+
+	post := func(token string) int {
+		return client.send(&Request{
+			Method:  http.MethodPost,
+			Headers: map[string][]string{"X-Csrf-Token": {token}},
+		}, func(*Request) Response {
+			return Ok("reached")
+		}).Status
+	}
+
+	if status := post(""); status != 403 {
+		t.Fatalf("expected a request without token to be rejected, got %d", status)
+	}
+
+	if status := post(token); status != 200 {
+		t.Fatalf("expected the session token to be accepted, got %d", status)
+	}
+}
+
+func TestCSRFSynchronizerRequiresSessions(t *testing.T) {
+	handler := CSRF(CSRFConfig{Mode: CSRFSynchronizer})(func(*Request) Response {
+		return Ok("reached")
+	})
+
+	if response := handler(&Request{Method: http.MethodGet}); response.Status != 500 {
+		t.Fatalf("expected 500 without sessions, got %d", response.Status)
+	}
+}
+
+func TestCSRFSynchronizerRotatesOnRegenerate(t *testing.T) {
+	client := &sessionClient{
+		config:      SessionConfig{Secret: testSessionSecret, Store: NewMemorySessionStore()},
+		middlewares: []Middleware{CSRF(CSRFConfig{Mode: CSRFSynchronizer})},
+	}
+
+	var before, after string
+	client.send(&Request{Method: http.MethodGet}, func(r *Request) Response {
+		before = CSRFToken(r)
+		return Ok("")
+	})
+
+	login := client.send(&Request{
+		Method:  http.MethodPost,
+		Headers: map[string][]string{"X-Csrf-Token": {before}},
+	}, func(r *Request) Response {
+		Session(r).Regenerate()
+		Session(r).Set("user", "alice")
+		after = CSRFToken(r)
+		return Ok("")
+	})
+
+	if login.Status != 200 {
+		t.Fatalf("expected the login to be accepted, got %d", login.Status)
+	}
+
+	post := func(token string) int {
+		return client.send(&Request{
+			Method:  http.MethodPost,
+			Headers: map[string][]string{"X-Csrf-Token": {token}},
+		}, func(*Request) Response {
+			return Ok("reached")
+		}).Status
+	}
+
+	if status := post(before); status != 403 {
+		t.Fatalf("expected the token issued before login to be rejected, got %d", status)
+	}
+
+	if status := post(after); status != 200 {
+		t.Fatalf("expected the token issued on login to be accepted, got %d", status)
+	}
+}
+
+type paymentController struct{}
+
+func (paymentController) Route() string {
+	return "/payments"
+}
+
+func (paymentController) Policies() PoliciesConfig {
+	return PoliciesConfig{
+		"PostNotification": {CSRFExempt()},
+	}
+}
+
+func (paymentController) PostNotification(*Request) Response {
+	return Ok("notified")
+}
+
+func (paymentController) PostRefund(*Request) Response {
+	return Ok("refunded")
+}
+
+func TestCSRFExempt(t *testing.T) {
+	router := NewDefaultRouter()
+	router.Use(CSRF(CSRFConfig{}))
+
+	webhooks := Group("/webhooks")
+	webhooks.UsePolicy(CSRFExempt())
+	webhooks.MapPost("/stripe", func(*Request) Response {
+		return Ok("received")
+	})
+	router.MapGroup(webhooks)
+
+	router.MapPost("/orders", func(*Request) Response {
+		return Ok("created")
+	})
+	router.MapController(paymentController{})
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/webhooks/stripe", status: 200},
+		{path: "/payments/notification", status: 200},
+		{path: "/payments/refund", status: 403},
+		{path: "/orders", status: 403},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, test.path, nil))
+			if w.Code != test.status {
+				t.Fatalf("expected %d, got %d", test.status, w.Code)
+			}
+		})
+	}
+}
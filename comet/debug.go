@@ -0,0 +1,185 @@
+package comet
+
+import (
+	"bytes"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"strings"
+)
+
+// DebugConfig configures the debug endpoints mapped with MapDebug
+type DebugConfig struct {
+	// Path where the endpoints are mounted, "/debug" by default
+	Path string
+	// Policies guarding every endpoint, like Authorize(Role, "admin").
+	// They rely on the authentication middlewares of the router.
+	Policies []Policy
+	// Middlewares applied to every endpoint
+	Middlewares []Middleware
+	// Address serves the endpoints on a separate listener started by Run,
+	// like "127.0.0.1:6060", instead of the router address
+	Address string
+}
+
+// MapDebug mounts the pprof profiles, the expvar variables, the list of
+// routes and a dump of the goroutines
+//
+//	{path}/pprof/       pprof index and profiles
+//	{path}/vars         expvar variables
+//	{path}/routes       routes of the router
+//	{path}/goroutines   stack traces of every goroutine
+//
+// The endpoints expose the application internals, so they must be
+// guarded with policies or served on an address not reachable publicly.
+// MapDebug panics when the config sets neither Policies nor Address.
+func (r *Router) MapDebug(config DebugConfig) {
+	if len(config.Policies) == 0 && config.Address == "" {
+		panic("comet: invalid debug configuration: the endpoints require policies or a separate address")
+	}
+
+	if config.Path == "" {
+		config.Path = "/debug"
+	}
+
+	group := Group(strings.TrimSuffix(config.Path, "/"))
+	for _, policy := range config.Policies {
+		group.UsePolicy(policy)
+	}
+	for _, middleware := range config.Middlewares {
+		group.Use(middleware)
+	}
+
+	index := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// pprof.Index only lists the profiles under /debug/pprof/
+		req.URL.Path = "/debug/pprof/"
+		pprof.Index(w, req)
+	}))
+
+	group.MapGet("/pprof", func(*Request) Response {
+		return Response{Status: http.StatusMovedPermanently}.WithHeader("Location", group.BasePath+"/pprof/")
+	})
+	group.MapGet("/pprof/", index)
+	group.MapGet("/pprof/cmdline", WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	group.MapGet("/pprof/profile", WrapHandler(http.HandlerFunc(pprof.Profile)))
+	group.MapGet("/pprof/symbol", WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	group.MapPost("/pprof/symbol", WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	group.MapGet("/pprof/trace", WrapHandler(http.HandlerFunc(pprof.Trace)))
+	group.MapGet("/pprof/:profile", func(req *Request) Response {
+		return WrapHandler(pprof.Handler(req.PathParams["profile"]))(req)
+	})
+	group.MapGet("/vars", WrapHandler(expvar.Handler()))
+	group.MapGet("/routes", func(*Request) Response {
+		return Ok(r.routes())
+	})
+	group.MapGet("/goroutines", func(*Request) Response {
+		var body bytes.Buffer
+		if err := runtimepprof.Lookup("goroutine").WriteTo(&body, 2); err != nil {
+			return Error(err.Error())
+		}
+		return Raw(200, "text/plain; charset=utf-8", body.Bytes())
+	})
+
+	if config.Address == "" {
+		r.MapGroup(group)
+		return
+	}
+
+	if r.debug == nil {
+		r.debug = newRouter()
+	}
+	r.debug.groups[group.BasePath] = group
+	r.debugAddress = config.Address
+}
+
+// WrapHandler adapts a net/http handler, buffering its response
+//
+//	router.MapGet("/files/:name", comet.WrapHandler(http.FileServer(fs)))
+func WrapHandler(handler http.Handler) RequestHandler {
+	return func(r *Request) Response {
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, r.Url.String(), bytes.NewReader(r.Body))
+		if err != nil {
+			return Error(err.Error())
+		}
+		req.Header = http.Header(r.Headers).Clone()
+		req.RemoteAddr = r.RemoteAddress
+
+		w := &bufferedResponse{header: make(http.Header)}
+		handler.ServeHTTP(w, req)
+
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		contentType := w.header.Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(w.body.Bytes())
+		}
+		w.header.Del("Content-Type")
+
+		return Response{
+			Status:  w.status,
+			Data:    Content{Type: contentType, Body: w.body.Bytes()},
+			Headers: w.header,
+		}
+	}
+}
+
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponse) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+type routeInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// routes lists the routes of the router sorted by path and method
+func (r *Router) routes() []routeInfo {
+	result := make([]routeInfo, 0)
+	for _, group := range r.router.groups {
+		for _, route := range group.DynamicRoutes {
+			result = append(result, routeInfo{Method: route.Method, Path: group.BasePath + route.PathPattern})
+		}
+
+		for key := range group.StaticRoutes {
+			method, path, _ := strings.Cut(key, ":")
+			result = append(result, routeInfo{Method: method, Path: group.BasePath + path})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Method < result[j].Method
+	})
+
+	return result
+}
+
+// debugHandler serves the debug endpoints mapped on a separate address,
+// running the router middlewares
+func (r *Router) debugHandler() http.Handler {
+	middlewares := chain(r.debug.Handle, r.middlewares...)
+	return r.requestScope(httpAdapter(r.debug.resolve(middlewares)))
+}
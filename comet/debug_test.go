@@ -0,0 +1,30 @@
+package comet
+
+import (
+	"testing"
+)
+
+func TestMapDebugRequiresGuard(t *testing.T) {
+	tests := []struct {
+		name   string
+		config DebugConfig
+		panics bool
+	}{
+		{name: "unguarded", config: DebugConfig{}, panics: true},
+		{name: "middlewares only", config: DebugConfig{Middlewares: []Middleware{RequestLogger()}}, panics: true},
+		{name: "policies", config: DebugConfig{Policies: []Policy{Authorize(RequireRole, "admin")}}},
+		{name: "address", config: DebugConfig{Address: "127.0.0.1:0"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if recovered := recover(); (recovered != nil) != test.panics {
+					t.Fatalf("expected panic %v, recovered %v", test.panics, recovered)
+				}
+			}()
+
+			NewDefaultRouter().MapDebug(test.config)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package comet
+
+import (
+	"bytes"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+// MapContainerDiagnostics serves the dependency graph of the request
+// container as JSON, or in the DOT language with ?format=dot. The graph
+// exposes the application internals, so the endpoint should be guarded
+// with middlewares or kept out of production builds.
+func (r *Router) MapContainerDiagnostics(path string, middlewares ...Middleware) {
+	r.MapGet(path, containerDiagnostics, middlewares...)
+}
+
+func containerDiagnostics(r *Request) Response {
+	container := ioc.FromContext(r.Context())
+
+	var body bytes.Buffer
+	if r.QueryParams["format"] != nil && r.QueryParams["format"][0] == "dot" {
+		if err := container.WriteDOT(&body); err != nil {
+			return Error(err.Error())
+		}
+		return Raw(200, "text/vnd.graphviz; charset=utf-8", body.Bytes())
+	}
+
+	if err := container.WriteJSON(&body); err != nil {
+		return Error(err.Error())
+	}
+	return Raw(200, "application/json", body.Bytes())
+}
@@ -12,6 +12,7 @@ type route struct {
 	Handler     RequestHandler
 	PathParts   []string
 	ParamNames  []string
+	Policies    []Policy
 }
 
 type CometGroup struct {
@@ -19,6 +20,9 @@ type CometGroup struct {
 	StaticRoutes  map[string]RequestHandler
 	DynamicRoutes []*route
 	Middlewares   []Middleware
+	Policies      []Policy
+
+	staticPolicies map[string][]Policy
 }
 
 func Group(basePath string) *CometGroup {
@@ -34,6 +38,11 @@ func (g *CometGroup) Use(middleware Middleware) {
 	g.Middlewares = append(g.Middlewares, middleware)
 }
 
+// UsePolicy applies a policy to every handler mapped afterwards in the group
+func (g *CometGroup) UsePolicy(policy Policy) {
+	g.Policies = append(g.Policies, policy)
+}
+
 func (g *CometGroup) MapGet(path string, handler RequestHandler, middlewares ...Middleware) {
 	g.mapRequestHandler(http.MethodGet, path, handler, middlewares...)
 }
@@ -55,6 +64,9 @@ func (g *CometGroup) MapDelete(path string, handler RequestHandler, middlewares
 }
 
 func (g *CometGroup) mapRequestHandler(method, path string, handler RequestHandler, middlewares ...Middleware) {
+	policies := append([]Policy(nil), g.Policies...)
+	handler = chainAuthorizations(handler, policyMap(policies))
+
 	if strings.Contains(path, ":") {
 		parts := strings.Split(path, "/")
 		params := make([]string, 0)
@@ -71,6 +83,7 @@ func (g *CometGroup) mapRequestHandler(method, path string, handler RequestHandl
 			Handler:     chain(chain(handler, middlewares...), g.Middlewares...),
 			PathParts:   parts,
 			ParamNames:  params,
+			Policies:    policies,
 		}
 
 		g.DynamicRoutes = append(g.DynamicRoutes, r)
@@ -79,4 +92,12 @@ func (g *CometGroup) mapRequestHandler(method, path string, handler RequestHandl
 
 	key := fmt.Sprintf("%s:%s", method, path)
 	g.StaticRoutes[key] = chain(chain(handler, middlewares...), g.Middlewares...)
+	g.setStaticPolicies(key, policies)
+}
+
+func (g *CometGroup) setStaticPolicies(key string, policies []Policy) {
+	if g.staticPolicies == nil {
+		g.staticPolicies = make(map[string][]Policy)
+	}
+	g.staticPolicies[key] = policies
 }
@@ -0,0 +1,33 @@
+package comet
+
+import (
+	"github.com/ramoncl001/go-comet/health"
+)
+
+// MapHealth serves the liveness and readiness probes of the checker, the
+// health default one when nil, responding 503 when any check is down.
+// Readiness also runs the health.Check services of the container, and
+// fails once Shutdown is called.
+//
+//	router.MapHealth("/health/live", "/health/ready", nil)
+func (r *Router) MapHealth(livePath, readyPath string, checker *health.Checker, middlewares ...Middleware) {
+	if checker == nil {
+		checker = health.Default()
+	}
+	r.checkers = append(r.checkers, checker)
+
+	r.MapGet(livePath, func(req *Request) Response {
+		return healthResponse(checker.Liveness(req.Context()))
+	}, middlewares...)
+
+	r.MapGet(readyPath, func(req *Request) Response {
+		return healthResponse(checker.Readiness(req.Context()))
+	}, middlewares...)
+}
+
+func healthResponse(report health.Report) Response {
+	if report.Status != health.StatusUp {
+		return Response{Status: 503, Data: report}
+	}
+	return Ok(report)
+}
@@ -0,0 +1,40 @@
+package comet
+
+import (
+	"github.com/ramoncl001/go-comet/logs"
+	"github.com/ramoncl001/go-comet/tracing"
+)
+
+// RequestLogger returns a middleware placing a request-scoped logger in the
+// request context, so every record logged with logs.FromContext(r.Context())
+// carries the route pattern, request ID and trace ID of the request. The
+// principal is added by the Authentication middleware, and the request ID
+// assigned by the RequestIDs middleware, when used, is preferred.
+func RequestLogger() Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			fields := make([]interface{}, 0, 6)
+			if route := r.Route(); route != "" {
+				fields = append(fields, "route", route)
+			}
+
+			// the ID validated by the RequestIDs middleware is already
+			// in the context fields, otherwise the header is used when valid
+			if _, ok := RequestIDFromContext(r.Context()); !ok {
+				if id := r.Header("X-Request-ID"); validRequestID(id, 128) {
+					fields = append(fields, "request_id", id)
+				}
+			}
+
+			if span := tracing.SpanFromContext(r.Context()); span != nil {
+				fields = append(fields, "trace_id", span.TraceID().String())
+			} else if parent, ok := tracing.ParseTraceparent(r.Header("traceparent")); ok {
+				fields = append(fields, "trace_id", parent.TraceID.String())
+			}
+
+			ctx := logs.WithFields(r.Context(), fields...)
+			ctx = logs.NewContext(ctx, logs.FromContext(ctx))
+			return next(r.WithContext(ctx))
+		}
+	}
+}
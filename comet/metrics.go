@@ -0,0 +1,77 @@
+package comet
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ramoncl001/go-comet/metrics"
+)
+
+// Metrics returns a middleware recording the requests count, latency and
+// requests in flight in the registry, the metrics default one when nil.
+// Requests are labelled by route pattern, so paths with parameters share
+// their series, and those matching no route are labelled "unmatched".
+func Metrics(registry *metrics.Registry) Middleware {
+	if registry == nil {
+		registry = metrics.Default()
+	}
+
+	requests := registry.Counter("http_requests_total", "Number of HTTP requests served.", "method", "route", "status")
+	duration := registry.Histogram("http_request_duration_seconds", "Latency of the HTTP requests.", nil, "method", "route", "status")
+	inFlight := registry.Gauge("http_requests_in_flight", "Number of HTTP requests being served.", "method", "route")
+
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			route := r.Route()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			method := methodLabel(r.Method)
+
+			start := time.Now()
+			inFlight.Inc(method, route)
+			defer inFlight.Dec(method, route)
+
+			response := next(r)
+
+			status := strconv.Itoa(response.Status)
+			requests.Inc(method, route, status)
+			duration.Observe(time.Since(start).Seconds(), method, route, status)
+
+			return response
+		}
+	}
+}
+
+// methodLabel returns the method, or "OTHER" for non standard methods,
+// so clients can't create an unbounded number of series
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// MapMetrics serves the metrics of the registry, the metrics default one
+// when nil, in the Prometheus text exposition format
+//
+//	router.Use(comet.Metrics(nil))
+//	router.MapMetrics("/metrics", nil)
+func (r *Router) MapMetrics(path string, registry *metrics.Registry, middlewares ...Middleware) {
+	if registry == nil {
+		registry = metrics.Default()
+	}
+
+	r.MapGet(path, func(*Request) Response {
+		var body bytes.Buffer
+		if _, err := registry.WriteTo(&body); err != nil {
+			return Error(err.Error())
+		}
+		return Raw(200, metrics.ContentType, body.Bytes())
+	}, middlewares...)
+}
@@ -0,0 +1,35 @@
+package comet
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/metrics"
+)
+
+func TestMetricsMethodLabel(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	router := NewDefaultRouter()
+	router.Use(Metrics(registry))
+
+	for _, method := range []string{"GET", "FOO", "BAR"} {
+		router.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/missing", nil))
+	}
+
+	var output bytes.Buffer
+	if _, err := registry.WriteTo(&output); err != nil {
+		t.Fatal(err)
+	}
+
+	text := output.String()
+	if strings.Contains(text, `method="FOO"`) || strings.Contains(text, `method="BAR"`) {
+		t.Fatalf("expected non standard methods to share a label, got\n%s", text)
+	}
+
+	if !strings.Contains(text, `http_requests_total{method="OTHER",route="unmatched",status="404"} 2`) {
+		t.Fatalf("expected the OTHER method label, got\n%s", text)
+	}
+}
@@ -0,0 +1,181 @@
+package comet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+// Module groups the services, routes and middlewares of a feature
+// so it can be shipped as a self-contained package
+//
+//	type OrdersModule struct{}
+//
+//	func (OrdersModule) Name() string        { return "orders" }
+//	func (OrdersModule) DependsOn() []string { return []string{"payments"} }
+//
+//	func (OrdersModule) Register(container *ioc.Container) error {
+//		container.RegisterScoped(ioc.TypeOf[OrderService](), NewOrderService)
+//		return nil
+//	}
+//
+//	func (OrdersModule) Map(routes *comet.ModuleRoutes) {
+//		routes.Use(comet.Authentication(scheme))
+//		routes.MapController(&OrdersController{})
+//	}
+type Module interface {
+	Name() string
+	// DependsOn names the modules configured before this one
+	DependsOn() []string
+	// Register adds the module services to the router container
+	Register(container *ioc.Container) error
+	// Map adds the module routes to the router
+	Map(routes *ModuleRoutes)
+}
+
+// ModuleRoutes maps the routes of a module. Middlewares added with Use
+// only apply to the module routes mapped afterwards.
+type ModuleRoutes struct {
+	router      *Router
+	middlewares []Middleware
+}
+
+func (m *ModuleRoutes) Use(middleware Middleware) {
+	m.middlewares = append(m.middlewares, middleware)
+}
+
+func (m *ModuleRoutes) MapGet(path string, handler RequestHandler, middlewares ...Middleware) {
+	m.router.MapGet(path, handler, m.with(middlewares)...)
+}
+
+func (m *ModuleRoutes) MapPost(path string, handler RequestHandler, middlewares ...Middleware) {
+	m.router.MapPost(path, handler, m.with(middlewares)...)
+}
+
+func (m *ModuleRoutes) MapPut(path string, handler RequestHandler, middlewares ...Middleware) {
+	m.router.MapPut(path, handler, m.with(middlewares)...)
+}
+
+func (m *ModuleRoutes) MapPatch(path string, handler RequestHandler, middlewares ...Middleware) {
+	m.router.MapPatch(path, handler, m.with(middlewares)...)
+}
+
+func (m *ModuleRoutes) MapDelete(path string, handler RequestHandler, middlewares ...Middleware) {
+	m.router.MapDelete(path, handler, m.with(middlewares)...)
+}
+
+// MapGroup maps the group wrapping its current routes with the module middlewares
+func (m *ModuleRoutes) MapGroup(group *CometGroup) {
+	if len(m.middlewares) > 0 {
+		for key, handler := range group.StaticRoutes {
+			group.StaticRoutes[key] = chain(handler, m.middlewares...)
+		}
+
+		for _, route := range group.DynamicRoutes {
+			route.Handler = chain(route.Handler, m.middlewares...)
+		}
+	}
+
+	m.router.MapGroup(group)
+}
+
+func (m *ModuleRoutes) MapController(controller ControllerBase, middlewares ...Middleware) {
+	m.router.MapController(controller, m.with(middlewares)...)
+}
+
+// with places the module middlewares ahead of the route ones
+func (m *ModuleRoutes) with(middlewares []Middleware) []Middleware {
+	return append(append([]Middleware(nil), m.middlewares...), middlewares...)
+}
+
+// AddModule adds a module to the router. Modules are configured when the
+// router runs, after the modules they depend on.
+func (r *Router) AddModule(module Module) {
+	r.modules = append(r.modules, module)
+}
+
+// configureModules registers the services and maps the routes of the
+// modules added since the last call, in dependency order
+func (r *Router) configureModules() error {
+	modules, err := sortModules(r.modules, r.configured)
+	if err != nil {
+		return err
+	}
+
+	for _, module := range modules {
+		if err := module.Register(r.container()); err != nil {
+			return fmt.Errorf("comet: module %s: %w", module.Name(), err)
+		}
+
+		module.Map(&ModuleRoutes{router: r})
+
+		if r.configured == nil {
+			r.configured = make(map[string]bool)
+		}
+		r.configured[module.Name()] = true
+	}
+
+	r.modules = nil
+	return nil
+}
+
+// sortModules orders the modules so every one comes after its
+// dependencies, keeping the order they were added otherwise
+func sortModules(modules []Module, configured map[string]bool) ([]Module, error) {
+	byName := make(map[string]Module, len(modules))
+	for _, module := range modules {
+		if _, ok := byName[module.Name()]; ok || configured[module.Name()] {
+			return nil, fmt.Errorf("comet: module %s added twice", module.Name())
+		}
+		byName[module.Name()] = module
+	}
+
+	result := make([]Module, 0, len(modules))
+	visited := make(map[string]bool, len(modules))
+	visiting := make([]string, 0)
+
+	var visit func(module Module) error
+	visit = func(module Module) error {
+		name := module.Name()
+		if visited[name] {
+			return nil
+		}
+
+		for i, pending := range visiting {
+			if pending == name {
+				cycle := append(append([]string(nil), visiting[i:]...), name)
+				return fmt.Errorf("comet: circular module dependency %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		visiting = append(visiting, name)
+		for _, dependency := range module.DependsOn() {
+			if configured[dependency] {
+				continue
+			}
+
+			next, ok := byName[dependency]
+			if !ok {
+				return fmt.Errorf("comet: module %s depends on missing module %s", name, dependency)
+			}
+
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		visiting = visiting[:len(visiting)-1]
+
+		visited[name] = true
+		result = append(result, module)
+		return nil
+	}
+
+	for _, module := range modules {
+		if err := visit(module); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,216 @@
+package comet
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type testModule struct {
+	name      string
+	deps      []string
+	register  func(container *ioc.Container) error
+	mapRoutes func(routes *ModuleRoutes)
+}
+
+func (m testModule) Name() string        { return m.name }
+func (m testModule) DependsOn() []string { return m.deps }
+
+func (m testModule) Register(container *ioc.Container) error {
+	if m.register == nil {
+		return nil
+	}
+	return m.register(container)
+}
+
+func (m testModule) Map(routes *ModuleRoutes) {
+	if m.mapRoutes != nil {
+		m.mapRoutes(routes)
+	}
+}
+
+func moduleNames(modules []Module) string {
+	names := make([]string, len(modules))
+	for i, module := range modules {
+		names[i] = module.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSortModules(t *testing.T) {
+	tests := []struct {
+		name       string
+		modules    []Module
+		configured map[string]bool
+		expected   string
+		err        string
+	}{
+		{
+			name:     "added order",
+			modules:  []Module{testModule{name: "a"}, testModule{name: "b"}, testModule{name: "c"}},
+			expected: "a,b,c",
+		},
+		{
+			name: "dependencies first",
+			modules: []Module{
+				testModule{name: "orders", deps: []string{"payments", "users"}},
+				testModule{name: "payments", deps: []string{"users"}},
+				testModule{name: "users"},
+			},
+			expected: "users,payments,orders",
+		},
+		{
+			name:       "configured dependency",
+			modules:    []Module{testModule{name: "orders", deps: []string{"users"}}},
+			configured: map[string]bool{"users": true},
+			expected:   "orders",
+		},
+		{
+			name: "cycle",
+			modules: []Module{
+				testModule{name: "a", deps: []string{"b"}},
+				testModule{name: "b", deps: []string{"c"}},
+				testModule{name: "c", deps: []string{"a"}},
+			},
+			err: "comet: circular module dependency a -> b -> c -> a",
+		},
+		{
+			name:    "self dependency",
+			modules: []Module{testModule{name: "a", deps: []string{"a"}}},
+			err:     "comet: circular module dependency a -> a",
+		},
+		{
+			name:    "missing dependency",
+			modules: []Module{testModule{name: "orders", deps: []string{"payments"}}},
+			err:     "comet: module orders depends on missing module payments",
+		},
+		{
+			name:    "duplicate",
+			modules: []Module{testModule{name: "orders"}, testModule{name: "orders"}},
+			err:     "comet: module orders added twice",
+		},
+		{
+			name:       "already configured",
+			modules:    []Module{testModule{name: "orders"}},
+			configured: map[string]bool{"orders": true},
+			err:        "comet: module orders added twice",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted, err := sortModules(test.modules, test.configured)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := moduleNames(sorted); got != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+// tag returns a middleware adding its name to the X-Middlewares header
+func tag(name string) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			return next(r).WithHeader("X-Middlewares", name)
+		}
+	}
+}
+
+func TestModuleRoutes(t *testing.T) {
+	ok := func(*Request) Response { return Ok("") }
+
+	router := NewDefaultRouter()
+	router.Container = ioc.New()
+	router.AddModule(testModule{
+		name: "orders",
+		mapRoutes: func(routes *ModuleRoutes) {
+			routes.MapGet("/public", ok)
+			routes.Use(tag("module"))
+			routes.MapGet("/orders", ok, tag("route"))
+
+			group := Group("/admin")
+			group.MapGet("/stats", ok)
+			group.MapGet("/stats/:day", ok)
+			routes.MapGroup(group)
+		},
+	})
+	router.MapGet("/health", ok)
+
+	if err := router.configureModules(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path        string
+		middlewares []string
+	}{
+		{path: "/public"},
+		{path: "/orders", middlewares: []string{"route", "module"}},
+		{path: "/admin/stats", middlewares: []string{"module"}},
+		{path: "/admin/stats/monday", middlewares: []string{"module"}},
+		{path: "/health"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.handler().ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != 200 {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+
+			if got := w.Result().Header.Values("X-Middlewares"); strings.Join(got, ",") != strings.Join(test.middlewares, ",") {
+				t.Fatalf("expected the middlewares %v, got %v", test.middlewares, got)
+			}
+		})
+	}
+}
+
+func TestConfigureModules(t *testing.T) {
+	var order []string
+	module := func(name string, deps ...string) testModule {
+		return testModule{name: name, deps: deps, register: func(*ioc.Container) error {
+			order = append(order, name)
+			return nil
+		}}
+	}
+
+	router := NewDefaultRouter()
+	router.Container = ioc.New()
+	router.AddModule(module("orders", "users"))
+	router.AddModule(module("users"))
+
+	if err := router.configureModules(); err != nil {
+		t.Fatal(err)
+	}
+
+	// modules added later may depend on the ones already configured
+	router.AddModule(module("billing", "orders"))
+	if err := router.configureModules(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(order, ","); got != "users,orders,billing" {
+		t.Fatalf("expected users,orders,billing, got %s", got)
+	}
+
+	failure := errors.New("invalid configuration")
+	router.AddModule(testModule{name: "broken", register: func(*ioc.Container) error { return failure }})
+	if err := router.configureModules(); !errors.Is(err, failure) || err.Error() != "comet: module broken: invalid configuration" {
+		t.Fatalf("expected the register error of the module, got %v", err)
+	}
+}
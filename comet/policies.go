@@ -19,3 +19,20 @@ func Authorize(fn AuthorizerFunction, val interface{}) Policy {
 type AuthorizerFunction = func(RequestHandler, interface{}) RequestHandler
 
 type AuthorizationMap map[interface{}]AuthorizerFunction
+
+func policyMap(policies []Policy) AuthorizationMap {
+	result := make(AuthorizationMap)
+	for _, val := range policies {
+		result[val.Value] = val.Validation
+	}
+	return result
+}
+
+func hasPolicy(policies []Policy, value interface{}) bool {
+	for _, policy := range policies {
+		if policy.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package comet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/ramoncl001/go-comet/logs"
+)
+
+type RequestIDFormat int
+
+const (
+	// RequestIDUUID generates random UUIDs (version 4)
+	RequestIDUUID RequestIDFormat = iota
+	// RequestIDULID generates ULIDs, sortable by creation time
+	RequestIDULID
+)
+
+// RequestIDConfig configures the RequestIDs middleware
+type RequestIDConfig struct {
+	Format RequestIDFormat
+	// Generate creates the IDs instead of Format, when set
+	Generate func() string
+	// HeaderName carrying the ID, "X-Request-ID" by default
+	HeaderName string
+	// MaxLength of the incoming IDs, 128 by default. Longer IDs, or
+	// IDs with characters other than letters, digits and "-_.:+=/",
+	// are replaced by a generated one.
+	MaxLength int
+	// IgnoreIncoming always generates a new ID, for services
+	// receiving requests from untrusted clients
+	IgnoreIncoming bool
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the given request ID,
+// which is also added to the records of its loggers
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = logs.WithFields(ctx, "request_id", id)
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// RequestID returns the ID assigned to the request by the RequestIDs
+// middleware, or an empty string
+func RequestID(r *Request) string {
+	id, _ := RequestIDFromContext(r.Context())
+	return id
+}
+
+// RequestIDs returns a middleware assigning an ID to every request, the
+// one received in the X-Request-ID header when valid or a generated one.
+// The ID is returned in the same header of the response, added to the
+// logs of the request and sent by RequestIDTransport.
+func RequestIDs(config RequestIDConfig) Middleware {
+	if config.HeaderName == "" {
+		config.HeaderName = "X-Request-ID"
+	}
+
+	if config.MaxLength <= 0 {
+		config.MaxLength = 128
+	}
+
+	if config.Generate == nil {
+		config.Generate = newUUID
+		if config.Format == RequestIDULID {
+			config.Generate = newULID
+		}
+	}
+
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			id := r.Header(config.HeaderName)
+			if config.IgnoreIncoming || !validRequestID(id, config.MaxLength) {
+				id = config.Generate()
+			}
+
+			response := next(r.WithContext(WithRequestID(r.Context(), id)))
+			if response.Headers.Get(config.HeaderName) == "" {
+				response = response.WithHeader(config.HeaderName, id)
+			}
+
+			return response
+		}
+	}
+}
+
+// validRequestID reports whether the ID can be used as is, so clients
+// can't inject arbitrary content in the logs and outgoing requests
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, char := range id {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		case char == '-' || char == '_' || char == '.' || char == ':' || char == '+' || char == '=' || char == '/':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newUUID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf[:])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a 48 bits millisecond timestamp followed by 80 random
+// bits, encoded in 26 characters of Crockford's base32
+func newULID() string {
+	var id [16]byte
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(time.Now().UnixMilli()))
+	copy(id[:6], timestamp[2:])
+	rand.Read(id[6:])
+
+	// 128 bits are encoded from the most significant 5 bits, the
+	// first character only holding the 3 bits left over
+	var buf [26]byte
+	high := binary.BigEndian.Uint64(id[:8])
+	low := binary.BigEndian.Uint64(id[8:])
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[low&0x1f]
+		low = low>>5 | high<<59
+		high >>= 5
+	}
+	return string(buf[:])
+}
+
+// RequestIDTransport is an http.RoundTripper sending the request ID of the
+// request context, so the services called share the ID in their logs
+//
+//	client := &http.Client{Transport: &comet.RequestIDTransport{}}
+type RequestIDTransport struct {
+	// Base performs the requests, http.DefaultTransport when nil
+	Base http.RoundTripper
+	// HeaderName carrying the ID, "X-Request-ID" by default
+	HeaderName string
+}
+
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	id, ok := RequestIDFromContext(req.Context())
+	if !ok {
+		return base.RoundTrip(req)
+	}
+
+	header := t.HeaderName
+	if header == "" {
+		header = "X-Request-ID"
+	}
+
+	// requests must not be modified by round trippers
+	req = req.Clone(req.Context())
+	req.Header.Set(header, id)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,204 @@
+package comet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewUUID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := newUUID()
+		if !pattern.MatchString(id) {
+			t.Fatalf("expected a version 4 UUID with the RFC 4122 variant, got %s", id)
+		}
+
+		if seen[id] {
+			t.Fatalf("duplicate UUID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+// decodeULID returns the 48 bits timestamp of a ULID
+func decodeULID(t *testing.T, id string) int64 {
+	t.Helper()
+
+	var timestamp int64
+	for _, char := range id[:10] {
+		index := strings.IndexRune(crockford, char)
+		if index < 0 {
+			t.Fatalf("invalid Crockford character %q in %s", char, id)
+		}
+		timestamp = timestamp<<5 | int64(index)
+	}
+	return timestamp
+}
+
+func TestNewULID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	before := time.Now().UnixMilli()
+	id := newULID()
+	after := time.Now().UnixMilli()
+
+	if !pattern.MatchString(id) {
+		t.Fatalf("expected 26 Crockford base32 characters, got %s", id)
+	}
+
+	if timestamp := decodeULID(t, id); timestamp < before || timestamp > after {
+		t.Fatalf("expected the timestamp between %d and %d, got %d", before, after, timestamp)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if later := newULID(); later <= id {
+		t.Fatalf("expected ULIDs to sort by creation time, got %s before %s", later, id)
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		maxLength int
+		valid     bool
+	}{
+		{name: "uuid", id: "3f2b8c1e-9d4a-4f6b-8e2a-1c5d7f9b0a3e", valid: true},
+		{name: "ulid", id: "01HV6Z3J8K9M2N4P5Q6R7S8T9V", valid: true},
+		{name: "allowed symbols", id: "a-b_c.d:e+f=g/h", valid: true},
+		{name: "max length", id: strings.Repeat("a", 16), maxLength: 16, valid: true},
+		{name: "empty", id: ""},
+		{name: "too long", id: strings.Repeat("a", 17), maxLength: 16},
+		{name: "space", id: "abc def"},
+		{name: "new line", id: "abc\nlevel=ERROR"},
+		{name: "quote", id: `abc"`},
+		{name: "non ascii", id: "abcé"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			maxLength := test.maxLength
+			if maxLength == 0 {
+				maxLength = 128
+			}
+
+			if valid := validRequestID(test.id, maxLength); valid != test.valid {
+				t.Fatalf("expected valid %v for %q", test.valid, test.id)
+			}
+		})
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulid := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+
+	tests := []struct {
+		name     string
+		config   RequestIDConfig
+		header   string
+		incoming string
+		expected *regexp.Regexp
+	}{
+		{name: "generated", expected: uuid},
+		{name: "ulid", config: RequestIDConfig{Format: RequestIDULID}, expected: ulid},
+		{name: "custom generator", config: RequestIDConfig{Generate: func() string { return "custom" }}, expected: regexp.MustCompile(`^custom$`)},
+		{name: "incoming", incoming: "client-id-1", expected: regexp.MustCompile(`^client-id-1$`)},
+		{name: "invalid incoming", incoming: "bad id", expected: uuid},
+		{name: "long incoming", config: RequestIDConfig{MaxLength: 4}, incoming: "12345", expected: uuid},
+		{name: "ignored incoming", config: RequestIDConfig{IgnoreIncoming: true}, incoming: "client-id-1", expected: uuid},
+		{name: "header name", config: RequestIDConfig{HeaderName: "X-Correlation-ID"}, header: "X-Correlation-ID", incoming: "corr-1", expected: regexp.MustCompile(`^corr-1$`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := test.header
+			if header == "" {
+				header = "X-Request-ID"
+			}
+
+			var seen string
+			handler := RequestIDs(test.config)(func(r *Request) Response {
+				seen = RequestID(r)
+				return Ok("")
+			})
+
+			headers := map[string][]string{}
+			if test.incoming != "" {
+				headers[http.CanonicalHeaderKey(header)] = []string{test.incoming}
+			}
+
+			response := handler(&Request{Headers: headers})
+			if !test.expected.MatchString(seen) {
+				t.Fatalf("expected the request ID to match %s, got %q", test.expected, seen)
+			}
+
+			if echoed := response.Headers.Get(header); echoed != seen {
+				t.Fatalf("expected %s to echo %q, got %q", header, seen, echoed)
+			}
+		})
+	}
+}
+
+func TestRequestIDsKeepsResponseHeader(t *testing.T) {
+	handler := RequestIDs(RequestIDConfig{})(func(*Request) Response {
+		return Ok("").WithHeader("X-Request-ID", "from-handler")
+	})
+
+	response := handler(&Request{Headers: map[string][]string{}})
+	if values := response.Headers.Values("X-Request-ID"); len(values) != 1 || values[0] != "from-handler" {
+		t.Fatalf("expected the handler header to be kept, got %v", values)
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	received := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		transport *RequestIDTransport
+		id        string
+		header    string
+	}{
+		{name: "default header", transport: &RequestIDTransport{}, id: "abc-1", header: "X-Request-ID"},
+		{name: "header name", transport: &RequestIDTransport{HeaderName: "X-Correlation-ID"}, id: "abc-2", header: "X-Correlation-ID"},
+		{name: "without id", transport: &RequestIDTransport{Base: http.DefaultTransport}, header: "X-Request-ID"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.id != "" {
+				req = req.WithContext(WithRequestID(req.Context(), test.id))
+			}
+
+			res, err := (&http.Client{Transport: test.transport}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if got := (<-received).Get(test.header); got != test.id {
+				t.Fatalf("expected %s %q, got %q", test.header, test.id, got)
+			}
+
+			if req.Header.Get(test.header) != "" {
+				t.Fatal("expected the original request not to be modified")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package comet
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 )
 
@@ -24,29 +25,85 @@ type Request struct {
 	UserAgent     string
 	RemoteAddress string
 	ctx           context.Context
+	state         *requestState
 }
 
 func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
 	return r.ctx
 }
 
 func (r *Request) WithContext(ctx context.Context) *Request {
-	return &Request{
-		ctx:         ctx,
-		Url:         r.Url,
-		Method:      r.Method,
-		QueryParams: r.QueryParams,
-		PathParams:  r.PathParams,
-		Headers:     r.Headers,
-		Body:        r.Body,
+	request := *r
+	request.ctx = ctx
+	return &request
+}
+
+// Route returns the pattern of the route matched for the request,
+// or an empty string when no route matches
+func (r *Request) Route() string {
+	if match := r.route(); match != nil {
+		return match.Pattern
 	}
+	return ""
+}
+
+func (r *Request) route() *matchedRoute {
+	if r.state == nil {
+		return nil
+	}
+	return r.state.route
+}
+
+// Header returns the first value of the given request header
+func (r *Request) Header(key string) string {
+	return http.Header(r.Headers).Get(key)
+}
+
+// Cookie returns the named cookie sent with the request
+func (r *Request) Cookie(name string) (*http.Cookie, error) {
+	request := &http.Request{Header: http.Header(r.Headers)}
+	return request.Cookie(name)
 }
 
 // Response represents the HTTP response to be sent to the client.
 // Provides methods to set status codes, headers, and response body content.
 type Response struct {
-	Status int
-	Data   interface{}
+	Status  int
+	Data    interface{}
+	Headers http.Header
+}
+
+// WithHeader returns a copy of the response with the given header added
+func (r Response) WithHeader(key, value string) Response {
+	headers := make(http.Header, len(r.Headers)+1)
+	for k, v := range r.Headers {
+		headers[k] = append([]string(nil), v...)
+	}
+	headers.Add(key, value)
+	r.Headers = headers
+	return r
+}
+
+// WithCookie returns a copy of the response setting the given cookie
+func (r Response) WithCookie(cookie *http.Cookie) Response {
+	return r.WithHeader("Set-Cookie", cookie.String())
+}
+
+// Content is a response body written as is instead of being encoded as JSON
+type Content struct {
+	Type string
+	Body []byte
+}
+
+// Raw returns a response writing body as is with the given content type
+func Raw(status int, contentType string, body []byte) Response {
+	return Response{
+		Status: status,
+		Data:   Content{Type: contentType, Body: body},
+	}
 }
 
 func Ok[T any](data T) Response {
@@ -91,6 +148,13 @@ func Unauthorized() Response {
 	}
 }
 
+func Forbidden() Response {
+	return Response{
+		Status: 403,
+		Data:   "Forbidden",
+	}
+}
+
 // RequestHandler is a function type that processes HTTP requests and generates responses.
 // The fundamental building block for defining API endpoints and handlers.
 type RequestHandler func(*Request) Response
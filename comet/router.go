@@ -2,50 +2,130 @@ package comet
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/ramoncl001/go-comet/tracing"
 )
 
 type router struct {
 	groups map[string]*CometGroup
 }
 
+// matchedRoute is the route selected for a request before the
+// middleware chain runs, so middlewares can inspect its policies
+type matchedRoute struct {
+	Pattern  string
+	Handler  RequestHandler
+	Params   map[string]string
+	Policies []Policy
+}
+
+// requestState is shared by every copy of a request created with WithContext
+type requestState struct {
+	route *matchedRoute
+	proto string
+	// afterWrite runs once the response has been written, with its
+	// status and the number of bytes of its body
+	afterWrite []func(status, size int)
+}
+
+func (s *requestState) written(status, size int) {
+	for _, hook := range s.afterWrite {
+		hook(status, size)
+	}
+}
+
 func (r *router) Handle(req *Request) Response {
-	path := req.Url.Path
+	match := req.route()
+	if match == nil {
+		match = r.match(req)
+	}
 
-	var group *CometGroup = nil
-	for key, val := range r.groups {
-		if strings.HasPrefix(path, key) {
-			group = val
-			break
+	if match == nil {
+		return NotFound()
+	}
+
+	req.PathParams = match.Params
+
+	// handlers are traced as part of the request span, if any
+	if tracing.SpanFromContext(req.Context()) != nil {
+		ctx, span := tracing.Start(req.Context(), "handler "+match.Pattern, tracing.KindInternal)
+		defer span.End()
+
+		response := match.Handler(req.WithContext(ctx))
+		if response.Status >= 500 {
+			span.SetStatus(tracing.StatusError, fmt.Sprint(response.Data))
 		}
+		return response
 	}
 
-	if group == nil {
-		return NotFound()
+	return match.Handler(req)
+}
+
+// resolve matches the request route ahead of the middleware chain
+func (r *router) resolve(next RequestHandler) RequestHandler {
+	return func(req *Request) Response {
+		if req.state == nil {
+			req.state = &requestState{}
+		}
+
+		req.state.route = r.match(req)
+		return next(req)
 	}
+}
 
-	path = strings.Replace(path, group.BasePath, "", 1)
+// match looks for the route in the groups with the longest base path first
+func (r *router) match(req *Request) *matchedRoute {
+	path := req.Url.Path
 
-	var handler RequestHandler
-	handler, ok := group.StaticRoutes[fmt.Sprintf("%s:%s", req.Method, path)]
-	if !ok {
-		for _, route := range group.DynamicRoutes {
-			if route.Method != req.Method {
-				continue
-			}
+	groups := make([]*CometGroup, 0, len(r.groups))
+	for _, group := range r.groups {
+		if strings.HasPrefix(path, group.BasePath) {
+			groups = append(groups, group)
+		}
+	}
 
-			if params, ok := r.matchPath(*route, path); ok {
-				req.PathParams = params
-				handler = route.Handler
-			}
+	sort.Slice(groups, func(i, j int) bool {
+		return len(groups[i].BasePath) > len(groups[j].BasePath)
+	})
+
+	for _, group := range groups {
+		if match := r.matchGroup(group, req.Method, strings.TrimPrefix(path, group.BasePath)); match != nil {
+			return match
 		}
 	}
 
-	if handler == nil {
-		return NotFound()
+	return nil
+}
+
+func (r *router) matchGroup(group *CometGroup, method, path string) *matchedRoute {
+	key := fmt.Sprintf("%s:%s", method, path)
+	if handler, ok := group.StaticRoutes[key]; ok {
+		return &matchedRoute{
+			Pattern:  group.BasePath + path,
+			Handler:  handler,
+			Params:   make(map[string]string),
+			Policies: group.staticPolicies[key],
+		}
+	}
+
+	for _, route := range group.DynamicRoutes {
+		if route.Method != method {
+			continue
+		}
+
+		if params, ok := r.matchPath(*route, path); ok {
+			return &matchedRoute{
+				Pattern:  group.BasePath + route.PathPattern,
+				Handler:  route.Handler,
+				Params:   params,
+				Policies: route.Policies,
+			}
+		}
 	}
 
-	return handler(req)
+	return nil
 }
 
 func newRouter() *router {
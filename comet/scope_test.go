@@ -0,0 +1,76 @@
+package comet
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type unitOfWork struct {
+	mu       sync.Mutex
+	disposed bool
+}
+
+func (u *unitOfWork) Dispose() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.disposed = true
+	return nil
+}
+
+func (u *unitOfWork) isDisposed() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.disposed
+}
+
+func TestRequestScope(t *testing.T) {
+	container := ioc.New()
+	container.RegisterScoped(ioc.TypeOf[*unitOfWork](), func() *unitOfWork {
+		return &unitOfWork{}
+	})
+
+	var instances []*unitOfWork
+	router := NewDefaultRouter()
+	router.Container = container
+	router.MapGet("/orders", func(r *Request) Response {
+		first, err := ioc.Resolve[*unitOfWork](r.Context())
+		if err != nil {
+			return Error(err.Error())
+		}
+
+		second, err := ioc.Resolve[*unitOfWork](r.Context())
+		if err != nil {
+			return Error(err.Error())
+		}
+
+		if first != second {
+			t.Error("expected the resolves of a request to share the scoped instance")
+		}
+
+		if first.isDisposed() {
+			t.Error("expected the scoped instance to live until the response is written")
+		}
+
+		instances = append(instances, first)
+		return Ok("orders")
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.handler().ServeHTTP(w, httptest.NewRequest("GET", "/orders", nil))
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+
+		if !instances[i].isDisposed() {
+			t.Fatalf("request %d: expected the scoped instance to be disposed once the response ends", i)
+		}
+	}
+
+	if instances[0] == instances[1] {
+		t.Fatal("expected every request to get its own scoped instance")
+	}
+}
@@ -1,26 +1,57 @@
 package comet
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
+
+	"github.com/ramoncl001/go-comet/health"
+	"github.com/ramoncl001/go-comet/ioc"
+	"github.com/ramoncl001/go-comet/logs"
 )
 
 type Router struct {
-	Address     string
+	Address string
+	// Container serving the dependencies of every request,
+	// the ioc default container when nil
+	Container *ioc.Container
+	// ValidateContainer makes Run refuse to start when the
+	// container registrations are not valid
+	ValidateContainer bool
+	// ShutdownDelay keeps serving requests for a while after Shutdown makes
+	// the readiness probes fail, so load balancers stop routing traffic first
+	ShutdownDelay time.Duration
+
 	server      *http.ServeMux
 	router      *router
 	middlewares []Middleware
+	modules     []Module
+	configured  map[string]bool
+	checkers    []*health.Checker
+
+	debug        *router
+	debugAddress string
+
+	// mu guards the servers, created by Run and stopped by Shutdown
+	mu          sync.Mutex
+	httpServer  *http.Server
+	debugServer *http.Server
+	closed      bool
 }
 
 func NewDefaultRouter() *Router {
 	return &Router{
 		Address:     ":5051",
+		Container:   ioc.Default(),
 		server:      http.NewServeMux(),
 		router:      newRouter(),
 		middlewares: make([]Middleware, 0),
@@ -65,6 +96,7 @@ func (r *Router) MapController(controller ControllerBase, middlewares ...Middlew
 
 	dynamicRoutes := make([]*route, 0)
 	staticRoutes := make(map[string]RequestHandler)
+	staticPolicies := make(map[string][]Policy)
 
 	policies := controller.Policies()
 	globalPolicies := policies["*"]
@@ -76,7 +108,7 @@ func (r *Router) MapController(controller ControllerBase, middlewares ...Middlew
 		}
 
 		invariantName := strings.ToUpper(method.Name)
-		methodMap := []requestMethod{get, post, delete, patch, put, list}
+		methodMap := []requestMethod{get, post, del, patch, put, list}
 
 		for _, prefix := range methodMap {
 			if !strings.HasPrefix(invariantName, prefix.string()) {
@@ -104,19 +136,17 @@ func (r *Router) MapController(controller ControllerBase, middlewares ...Middlew
 				config = append(config, methodPolicies...)
 			}
 
-			policyMap := make(map[interface{}]AuthorizerFunction)
-			for _, val := range config {
-				policyMap[val.Value] = val.Validation
-			}
+			relativePath := strings.Replace(path, basePath, "", 1)
 
 			if !strings.Contains(path, ":") {
-				key := fmt.Sprintf("%s:%s", httpMethod, strings.Replace(path, basePath, "", 1))
-				handler = chainAuthorizations(handler, policyMap)
+				key := fmt.Sprintf("%s:%s", httpMethod, relativePath)
+				handler = chainAuthorizations(handler, policyMap(config))
 				staticRoutes[key] = chain(handler, middlewares...)
+				staticPolicies[key] = config
 				break
 			}
 
-			parts := strings.Split(path, "/")
+			parts := strings.Split(relativePath, "/")
 			params := make([]string, 0)
 
 			for _, part := range parts {
@@ -125,14 +155,15 @@ func (r *Router) MapController(controller ControllerBase, middlewares ...Middlew
 				}
 			}
 
-			handler = chainAuthorizations(handler, policyMap)
+			handler = chainAuthorizations(handler, policyMap(config))
 
 			dynamicRoutes = append(dynamicRoutes, &route{
 				Method:      httpMethod,
-				PathPattern: path,
+				PathPattern: relativePath,
 				Handler:     chain(handler, middlewares...),
 				PathParts:   parts,
 				ParamNames:  params,
+				Policies:    config,
 			})
 		}
 
@@ -143,28 +174,134 @@ func (r *Router) MapController(controller ControllerBase, middlewares ...Middlew
 		StaticRoutes:  staticRoutes,
 		DynamicRoutes: dynamicRoutes,
 		Middlewares:   make([]Middleware, 0),
+
+		staticPolicies: staticPolicies,
 	}
 }
 
+// Run starts serving requests, returning http.ErrServerClosed once
+// Shutdown is called, even if it is called before the server started
 func (r *Router) Run() error {
+	// the servers are created first, so a Shutdown called while the
+	// container initializes finds them and stops them before they serve
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return http.ErrServerClosed
+	}
+
+	if r.httpServer != nil {
+		r.mu.Unlock()
+		return errors.New("comet: router is already running")
+	}
+
+	r.httpServer = &http.Server{
+		Addr:    r.Address,
+		Handler: r.server,
+	}
+
+	if r.debug != nil {
+		r.debugServer = &http.Server{Addr: r.debugAddress}
+	}
+	httpServer, debugServer := r.httpServer, r.debugServer
+	r.mu.Unlock()
+
+	if err := r.configureModules(); err != nil {
+		return err
+	}
+
+	if r.ValidateContainer {
+		if err := r.container().Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.container().Initialize(context.Background()); err != nil {
+		return err
+	}
+
 	fmt.Printf("Starting server in %s...\n", r.Address)
 	fmt.Println("Routes...")
-	for _, group := range r.router.groups {
-		for _, route := range group.DynamicRoutes {
-			fmt.Printf("[%s]: %s%s\n", route.Method, group.BasePath, route.PathPattern)
-		}
+	for _, route := range r.routes() {
+		fmt.Printf("[%s]: %s\n", route.Method, route.Path)
+	}
+
+	r.server.Handle("/", r.handler())
+
+	if debugServer != nil {
+		debugServer.Handler = r.debugHandler()
+
+		fmt.Printf("Serving debug endpoints in %s...\n", r.debugAddress)
+		go func() {
+			if err := debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logs.FromContext(context.Background()).Error("debug server stopped", "error", err)
+			}
+		}()
+	}
+
+	return httpServer.ListenAndServe()
+}
 
-		for key := range group.StaticRoutes {
-			method := strings.Split(key, ":")[0]
-			path := strings.Replace(key, method+":", "", 1)
-			fmt.Printf("[%s]: %s%s\n", method, group.BasePath, path)
+// Shutdown gracefully stops the server started by Run, waiting for the
+// active requests to complete, and then disposes the router container.
+// The readiness probes mapped with MapHealth fail from the start. Run
+// returns http.ErrServerClosed once the router has been shut down.
+func (r *Router) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	httpServer, debugServer := r.httpServer, r.debugServer
+	r.mu.Unlock()
+
+	for _, checker := range r.checkers {
+		checker.SetShuttingDown(true)
+	}
+
+	if r.ShutdownDelay > 0 && httpServer != nil {
+		select {
+		case <-time.After(r.ShutdownDelay):
+		case <-ctx.Done():
 		}
 	}
 
+	var err, debugErr error
+	if httpServer != nil {
+		err = httpServer.Shutdown(ctx)
+	}
+
+	if debugServer != nil {
+		debugErr = debugServer.Shutdown(ctx)
+	}
+
+	return errors.Join(err, debugErr, r.container().Close())
+}
+
+// handler builds the request pipeline, matching the route before
+// running the middlewares
+func (r *Router) handler() http.Handler {
 	middlewares := chain(r.router.Handle, r.middlewares...)
-	r.server.Handle("/", httpAdapter(middlewares))
+	return r.requestScope(httpAdapter(r.router.resolve(middlewares)))
+}
 
-	return http.ListenAndServe(r.Address, r.server)
+// requestScope binds the router container to every request and opens a
+// dependency scope that is closed once the response has been written
+func (r *Router) requestScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, scope := ioc.NewScope(ioc.WithContainer(req.Context(), r.container()))
+		defer func() {
+			if err := scope.Close(); err != nil {
+				logs.FromContext(ctx).Error("error disposing request scope", "error", err)
+			}
+		}()
+
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+func (r *Router) container() *ioc.Container {
+	if r.Container == nil {
+		return ioc.Default()
+	}
+	return r.Container
 }
 
 var httpAdapter = func(next RequestHandler) http.HandlerFunc {
@@ -185,18 +322,34 @@ var httpAdapter = func(next RequestHandler) http.HandlerFunc {
 			Body:          bytes,
 			UserAgent:     r.UserAgent(),
 			RemoteAddress: r.RemoteAddr,
+			ctx:           r.Context(),
+			state:         &requestState{proto: r.Proto},
 		}
 
 		response := next(request)
 
-		responseBytes, err := json.Marshal(response.Data)
-		if err != nil {
-			http.Error(w, "error deserializing response", 500)
-			return
+		var responseBytes []byte
+		if content, ok := response.Data.(Content); ok {
+			responseBytes = content.Body
+			w.Header().Set("Content-Type", content.Type)
+		} else {
+			responseBytes, err = json.Marshal(response.Data)
+			if err != nil {
+				http.Error(w, "error deserializing response", 500)
+				request.state.written(500, len("error deserializing response\n"))
+				return
+			}
+		}
+
+		for key, values := range response.Headers {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
 		}
 
 		w.WriteHeader(response.Status)
-		w.Write(responseBytes)
+		size, _ := w.Write(responseBytes)
+		request.state.written(response.Status, size)
 	})
 }
 
@@ -222,7 +375,7 @@ func isRequestMethod(method reflect.Method) bool {
 		return false
 	}
 
-	validPrefixes := []requestMethod{get, post, put, patch, delete, list}
+	validPrefixes := []requestMethod{get, post, put, patch, del, list}
 
 	methodName := strings.ToUpper(method.Name)
 	matchPrefix := false
@@ -261,7 +414,7 @@ func isRequestMethod(method reflect.Method) bool {
 		return false
 	}
 
-	return reqType.Name() == "Request" && strings.Contains(reqType.PkgPath(), "github.com/ramoncl001/go-comet/rest")
+	return reqType == reflect.TypeOf(Request{})
 }
 
 func getMethodPath(basePath, methodName string) string {
@@ -0,0 +1,50 @@
+package comet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+func TestShutdownRightAfterRun(t *testing.T) {
+	router := NewDefaultRouter()
+	router.Address = "127.0.0.1:0"
+	router.Container = ioc.New()
+	router.ShutdownDelay = 20 * time.Millisecond
+
+	result := make(chan error, 1)
+	go func() {
+		result <- router.Run()
+	}()
+
+	if err := router.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, http.ErrServerClosed) {
+			t.Fatalf("expected http.ErrServerClosed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run kept serving after Shutdown")
+	}
+}
+
+func TestRunAfterShutdown(t *testing.T) {
+	router := NewDefaultRouter()
+	router.Address = "127.0.0.1:0"
+	router.Container = ioc.New()
+
+	if err := router.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := router.Run(); !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("expected http.ErrServerClosed, got %v", err)
+	}
+}
@@ -0,0 +1,391 @@
+package comet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionConfig configures the Sessions middleware
+type SessionConfig struct {
+	// CookieName is the name of the session cookie, "comet_session" by default
+	CookieName string
+	// Secret signs the session cookie. It is required.
+	Secret []byte
+	// EncryptionKey enables AES-GCM encryption of cookie stored sessions.
+	// It must be 16, 24 or 32 bytes long.
+	EncryptionKey []byte
+	// Store keeps sessions server-side, leaving only the signed session ID
+	// in the cookie. When nil the whole session is stored in the cookie.
+	Store SessionStore
+	// IdleTimeout expires sessions not used for the given duration, 30 minutes by default
+	IdleTimeout time.Duration
+	// AbsoluteTimeout expires sessions created before the given duration, 24 hours by default
+	AbsoluteTimeout time.Duration
+
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// SessionRecord is the persisted state of a session
+type SessionRecord struct {
+	ID         string                   `json:"id"`
+	Values     map[string]interface{}   `json:"values,omitempty"`
+	Flashes    map[string][]interface{} `json:"flashes,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+	LastAccess time.Time                `json:"last_access"`
+}
+
+// SessionState is the session attached to a request. Values are serialized
+// as JSON, so numbers are read back as float64 and structs as maps.
+type SessionState struct {
+	mu        sync.Mutex
+	record    *SessionRecord
+	previous  string
+	isNew     bool
+	modified  bool
+	destroyed bool
+	// rotated are the keys removed by Regenerate
+	rotated []string
+}
+
+type sessionKey struct{}
+
+// Session returns the session of the request, or nil when the
+// Sessions middleware is not installed
+func Session(r *Request) *SessionState {
+	session, _ := r.Context().Value(sessionKey{}).(*SessionState)
+	return session
+}
+
+// ID returns the current session identifier
+func (s *SessionState) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record.ID
+}
+
+// IsNew reports whether the session was created by the current request
+func (s *SessionState) IsNew() bool {
+	return s.isNew
+}
+
+func (s *SessionState) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record.Values[key]
+}
+
+func (s *SessionState) GetString(key string) string {
+	value, _ := s.Get(key).(string)
+	return value
+}
+
+func (s *SessionState) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.record.Values == nil {
+		s.record.Values = make(map[string]interface{})
+	}
+	s.record.Values[key] = value
+	s.modified = true
+}
+
+func (s *SessionState) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.record.Values, key)
+	s.modified = true
+}
+
+// Clear removes every value and flash message from the session
+func (s *SessionState) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.record.Values = nil
+	s.record.Flashes = nil
+	s.modified = true
+}
+
+// Flash adds a message that lives until it is read with Flashes
+func (s *SessionState) Flash(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.record.Flashes == nil {
+		s.record.Flashes = make(map[string][]interface{})
+	}
+	s.record.Flashes[key] = append(s.record.Flashes[key], value)
+	s.modified = true
+}
+
+// Flashes returns and removes the flash messages stored under key
+func (s *SessionState) Flashes(key string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flashes, ok := s.record.Flashes[key]
+	if !ok {
+		return nil
+	}
+
+	delete(s.record.Flashes, key)
+	s.modified = true
+	return flashes
+}
+
+// Regenerate assigns a new identifier to the session keeping its values.
+// It must be called whenever the privilege level changes, such as on login,
+// to prevent session fixation. Values bound to the previous privilege
+// level, like the synchronizer CSRF token, are removed.
+func (s *SessionState) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.previous == "" && !s.isNew {
+		s.previous = s.record.ID
+	}
+	for _, key := range s.rotated {
+		delete(s.record.Values, key)
+	}
+	s.record.ID = newSessionID()
+	s.record.CreatedAt = time.Now()
+	s.modified = true
+}
+
+// rotateOnRegenerate removes the value of key when the session is
+// regenerated, for values bound to the privilege level like CSRF tokens
+func (s *SessionState) rotateOnRegenerate(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotated = append(s.rotated, key)
+}
+
+// Destroy removes the session from the store and expires its cookie
+func (s *SessionState) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.destroyed = true
+}
+
+// Sessions returns a middleware that loads the session of every request,
+// exposes it through Session and persists it once the handler returns.
+// It panics when the configuration is invalid.
+func Sessions(config SessionConfig) Middleware {
+	manager := newSessionManager(config)
+
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			session := manager.load(r)
+
+			ctx := context.WithValue(r.Context(), sessionKey{}, session)
+			response := next(r.WithContext(ctx))
+
+			cookie, err := manager.save(r.Context(), session)
+			if err != nil {
+				return Error("error saving session")
+			}
+
+			if cookie != nil {
+				response = response.WithCookie(cookie)
+			}
+
+			return response
+		}
+	}
+}
+
+type sessionManager struct {
+	config SessionConfig
+	codec  *cookieCodec
+}
+
+func newSessionManager(config SessionConfig) *sessionManager {
+	if config.CookieName == "" {
+		config.CookieName = "comet_session"
+	}
+
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30 * time.Minute
+	}
+
+	if config.AbsoluteTimeout <= 0 {
+		config.AbsoluteTimeout = 24 * time.Hour
+	}
+
+	if config.Path == "" {
+		config.Path = "/"
+	}
+
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+
+	codec, err := newCookieCodec(config.Secret, config.EncryptionKey)
+	if err != nil {
+		panic("comet: invalid session configuration: " + err.Error())
+	}
+
+	return &sessionManager{
+		config: config,
+		codec:  codec,
+	}
+}
+
+func (m *sessionManager) load(r *Request) *SessionState {
+	now := time.Now()
+
+	if record := m.read(r); record != nil && !m.expired(record, now) {
+		record.LastAccess = now
+		return &SessionState{record: record}
+	}
+
+	return &SessionState{
+		record: &SessionRecord{
+			ID:         newSessionID(),
+			CreatedAt:  now,
+			LastAccess: now,
+		},
+		isNew: true,
+	}
+}
+
+func (m *sessionManager) read(r *Request) *SessionRecord {
+	cookie, err := r.Cookie(m.config.CookieName)
+	if err != nil {
+		return nil
+	}
+
+	payload, err := m.codec.decode(m.config.CookieName, cookie.Value)
+	if err != nil {
+		return nil
+	}
+
+	if m.config.Store == nil {
+		record := &SessionRecord{}
+		if err := unmarshalSession(payload, record); err != nil {
+			return nil
+		}
+		return record
+	}
+
+	record, err := m.config.Store.Load(r.Context(), string(payload))
+	if err != nil {
+		return nil
+	}
+
+	return record
+}
+
+func (m *sessionManager) expired(record *SessionRecord, now time.Time) bool {
+	return now.Sub(record.LastAccess) > m.config.IdleTimeout ||
+		now.Sub(record.CreatedAt) > m.config.AbsoluteTimeout
+}
+
+func (s *SessionState) snapshot() (SessionRecord, string, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.record, s.previous, s.modified, s.destroyed
+}
+
+// save persists the session and returns the cookie to send, if any.
+// Untouched new sessions are not persisted to avoid creating one per visitor.
+func (m *sessionManager) save(ctx context.Context, session *SessionState) (*http.Cookie, error) {
+	record, previous, modified, destroyed := session.snapshot()
+	store := m.config.Store
+
+	if destroyed {
+		if session.isNew {
+			return nil, nil
+		}
+
+		if store != nil {
+			if err := store.Delete(ctx, record.ID); err != nil {
+				return nil, err
+			}
+			if previous != "" {
+				if err := store.Delete(ctx, previous); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return m.cookie("", -1), nil
+	}
+
+	if session.isNew && !modified {
+		return nil, nil
+	}
+
+	if store == nil {
+		payload, err := marshalSession(&record)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := m.codec.encode(m.config.CookieName, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		return m.cookie(value, 0), nil
+	}
+
+	if previous != "" {
+		if err := store.Delete(ctx, previous); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := store.Save(ctx, &record, m.ttl(&record)); err != nil {
+		return nil, err
+	}
+
+	value, err := m.codec.encode(m.config.CookieName, []byte(record.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	return m.cookie(value, 0), nil
+}
+
+// ttl returns how long the record may live in a store before one of the timeouts expires it
+func (m *sessionManager) ttl(record *SessionRecord) time.Duration {
+	idle := time.Until(record.LastAccess.Add(m.config.IdleTimeout))
+	absolute := time.Until(record.CreatedAt.Add(m.config.AbsoluteTimeout))
+	if absolute < idle {
+		return absolute
+	}
+	return idle
+}
+
+func (m *sessionManager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    value,
+		Path:     m.config.Path,
+		Domain:   m.config.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.config.Secure,
+		HttpOnly: true,
+		SameSite: m.config.SameSite,
+	}
+}
+
+func newSessionID() string {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		panic("comet: unable to generate session id: " + err.Error())
+	}
+	return hex.EncodeToString(id)
+}
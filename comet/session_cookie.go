@@ -0,0 +1,107 @@
+package comet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	errMissingSessionSecret = errors.New("session secret is required")
+	errInvalidCookie        = errors.New("invalid cookie value")
+)
+
+// cookieCodec signs cookie values with HMAC-SHA256 and optionally
+// encrypts them with AES-GCM before signing
+type cookieCodec struct {
+	secret []byte
+	aead   cipher.AEAD
+}
+
+func newCookieCodec(secret, encryptionKey []byte) (*cookieCodec, error) {
+	if len(secret) == 0 {
+		return nil, errMissingSessionSecret
+	}
+
+	codec := &cookieCodec{secret: secret}
+	if len(encryptionKey) == 0 {
+		return codec, nil
+	}
+
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	codec.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec, nil
+}
+
+func (c *cookieCodec) encode(name string, payload []byte) (string, error) {
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		payload = c.aead.Seal(nonce, nonce, payload, []byte(name))
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(payload)
+	return value + "." + c.sign(name, value), nil
+}
+
+func (c *cookieCodec) decode(name, cookie string) ([]byte, error) {
+	index := strings.LastIndexByte(cookie, '.')
+	if index < 0 {
+		return nil, errInvalidCookie
+	}
+
+	value, signature := cookie[:index], cookie[index+1:]
+	if !hmac.Equal([]byte(signature), []byte(c.sign(name, value))) {
+		return nil, errInvalidCookie
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCookie
+	}
+
+	if c.aead == nil {
+		return payload, nil
+	}
+
+	size := c.aead.NonceSize()
+	if len(payload) < size {
+		return nil, errInvalidCookie
+	}
+
+	return c.aead.Open(nil, payload[:size], payload[size:], []byte(name))
+}
+
+// sign binds the value to the cookie name so a signed value
+// cannot be replayed in a different cookie
+func (c *cookieCodec) sign(name, value string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func marshalSession(record *SessionRecord) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func unmarshalSession(data []byte, record *SessionRecord) error {
+	return json.Unmarshal(data, record)
+}
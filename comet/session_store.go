@@ -0,0 +1,212 @@
+package comet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var errInvalidSessionID = errors.New("invalid session id")
+
+// SessionStore keeps sessions server-side. Load returns a nil record
+// without error when the session does not exist or has expired.
+type SessionStore interface {
+	Load(ctx context.Context, id string) (*SessionRecord, error)
+	Save(ctx context.Context, record *SessionRecord, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+type storedSession struct {
+	Record    *SessionRecord `json:"record"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// MemorySessionStore keeps sessions in process memory. Sessions are
+// lost on restart and not shared between instances.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]storedSession
+	saves    int
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]storedSession),
+	}
+}
+
+func (s *MemorySessionStore) Load(_ context.Context, id string) (*SessionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		delete(s.sessions, id)
+		return nil, nil
+	}
+
+	return copySessionRecord(stored.Record)
+}
+
+func (s *MemorySessionStore) Save(_ context.Context, record *SessionRecord, ttl time.Duration) error {
+	record, err := copySessionRecord(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[record.ID] = storedSession{
+		Record:    record,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	// expired sessions are swept periodically instead of on a timer
+	s.saves++
+	if s.saves%1000 == 0 {
+		now := time.Now()
+		for id, stored := range s.sessions {
+			if now.After(stored.ExpiresAt) {
+				delete(s.sessions, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// FileSessionStore keeps every session as a JSON file inside a directory
+type FileSessionStore struct {
+	dir string
+}
+
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileSessionStore{dir: dir}, nil
+}
+
+func (s *FileSessionStore) Load(_ context.Context, id string) (*SessionRecord, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored := storedSession{}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, os.Remove(path)
+	}
+
+	return stored.Record, nil
+}
+
+func (s *FileSessionStore) Save(_ context.Context, record *SessionRecord, ttl time.Duration) error {
+	path, err := s.path(record.ID)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(storedSession{
+		Record:    record,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	// written to a temporary file and renamed so concurrent readers
+	// never observe a partially written session
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileSessionStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path validates the session ID before using it as a file name
+func (s *FileSessionStore) path(id string) (string, error) {
+	if len(id) == 0 || len(id) > 128 {
+		return "", errInvalidSessionID
+	}
+
+	for _, char := range id {
+		if !(char >= '0' && char <= '9' || char >= 'a' && char <= 'f') {
+			return "", errInvalidSessionID
+		}
+	}
+
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// copySessionRecord deep copies a record so stored sessions are
+// not mutated by the requests using them
+func copySessionRecord(record *SessionRecord) (*SessionRecord, error) {
+	data, err := marshalSession(record)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SessionRecord{}
+	if err := unmarshalSession(data, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,376 @@
+package comet
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSessionSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// sessionClient sends requests through the Sessions middleware and the
+// given middlewares, keeping the session cookie like a browser
+type sessionClient struct {
+	config      SessionConfig
+	middlewares []Middleware
+	cookie      *http.Cookie
+}
+
+func (c *sessionClient) do(action func(*SessionState) Response) Response {
+	return c.send(&Request{Method: http.MethodGet}, func(r *Request) Response {
+		return action(Session(r))
+	})
+}
+
+func (c *sessionClient) send(r *Request, handler RequestHandler) Response {
+	handler = Sessions(c.config)(chain(handler, c.middlewares...))
+
+	if r.Headers == nil {
+		r.Headers = map[string][]string{}
+	}
+	if c.cookie != nil {
+		r.Headers["Cookie"] = []string{c.cookie.Name + "=" + c.cookie.Value}
+	}
+
+	response := handler(r)
+	for _, cookie := range (&http.Response{Header: response.Headers}).Cookies() {
+		if cookie.Name != "comet_session" {
+			continue
+		}
+
+		c.cookie = cookie
+		if cookie.MaxAge < 0 {
+			c.cookie = nil
+		}
+	}
+
+	return response
+}
+
+func (c *sessionClient) set(key, value string) string {
+	var id string
+	c.do(func(s *SessionState) Response {
+		s.Set(key, value)
+		id = s.ID()
+		return Ok("")
+	})
+	return id
+}
+
+func (c *sessionClient) get(key string) (string, string) {
+	var id, value string
+	c.do(func(s *SessionState) Response {
+		id, value = s.ID(), s.GetString(key)
+		return Ok("")
+	})
+	return id, value
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SessionConfig
+	}{
+		{name: "signed cookie", config: SessionConfig{Secret: testSessionSecret}},
+		{name: "encrypted cookie", config: SessionConfig{Secret: testSessionSecret, EncryptionKey: testSessionSecret}},
+		{name: "memory store", config: SessionConfig{Secret: testSessionSecret, Store: NewMemorySessionStore()}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &sessionClient{config: test.config}
+			id := client.set("user", "alice")
+			if client.cookie == nil {
+				t.Fatal("expected a session cookie")
+			}
+
+			if !client.cookie.HttpOnly || client.cookie.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("unexpected cookie attributes %v", client.cookie)
+			}
+
+			if got, value := client.get("user"); got != id || value != "alice" {
+				t.Fatalf("expected session %s with alice, got %s with %q", id, got, value)
+			}
+		})
+	}
+}
+
+func TestSessionEncryptedCookieHidesValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       []byte
+		plaintext bool
+	}{
+		{name: "signed", plaintext: true},
+		{name: "encrypted", key: testSessionSecret},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &sessionClient{config: SessionConfig{Secret: testSessionSecret, EncryptionKey: test.key}}
+			client.set("user", "alice")
+
+			value := client.cookie.Value[:strings.LastIndexByte(client.cookie.Value, '.')]
+			payload, err := base64.RawURLEncoding.DecodeString(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(string(payload), "alice") != test.plaintext {
+				t.Fatalf("unexpected cookie payload %q", payload)
+			}
+		})
+	}
+}
+
+func TestSessionTamperedCookie(t *testing.T) {
+	tamper := []struct {
+		name   string
+		modify func(value string) string
+	}{
+		{name: "payload", modify: func(value string) string {
+			if value[0] == 'A' {
+				return "B" + value[1:]
+			}
+			return "A" + value[1:]
+		}},
+		{name: "signature", modify: func(value string) string { return value[:len(value)-2] + "xx" }},
+		{name: "unsigned", modify: func(value string) string { return value[:strings.LastIndexByte(value, '.')] }},
+		{name: "other secret", modify: func(value string) string {
+			codec, _ := newCookieCodec([]byte("another secret"), nil)
+			forged, _ := codec.encode("comet_session", []byte(`{"id":"forged","values":{"user":"mallory"}}`))
+			return forged
+		}},
+	}
+
+	configs := []struct {
+		name   string
+		config SessionConfig
+	}{
+		{name: "signed", config: SessionConfig{Secret: testSessionSecret}},
+		{name: "encrypted", config: SessionConfig{Secret: testSessionSecret, EncryptionKey: testSessionSecret}},
+	}
+
+	for _, config := range configs {
+		for _, test := range tamper {
+			t.Run(config.name+" "+test.name, func(t *testing.T) {
+				client := &sessionClient{config: config.config}
+				id := client.set("user", "alice")
+
+				client.cookie.Value = test.modify(client.cookie.Value)
+				if got, value := client.get("user"); got == id || value != "" {
+					t.Fatalf("expected the tampered cookie to be rejected, got session %s with %q", got, value)
+				}
+			})
+		}
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		createdAt  time.Time
+		lastAccess time.Time
+		expired    bool
+	}{
+		{name: "active", createdAt: now.Add(-time.Hour), lastAccess: now.Add(-time.Minute)},
+		{name: "idle", createdAt: now.Add(-time.Hour), lastAccess: now.Add(-31 * time.Minute), expired: true},
+		{name: "absolute", createdAt: now.Add(-25 * time.Hour), lastAccess: now.Add(-time.Minute), expired: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemorySessionStore()
+			record := &SessionRecord{
+				ID:         newSessionID(),
+				Values:     map[string]interface{}{"user": "alice"},
+				CreatedAt:  test.createdAt,
+				LastAccess: test.lastAccess,
+			}
+			store.Save(context.Background(), record, time.Hour)
+
+			client := &sessionClient{config: SessionConfig{Secret: testSessionSecret, Store: store}}
+			codec, _ := newCookieCodec(testSessionSecret, nil)
+			value, _ := codec.encode("comet_session", []byte(record.ID))
+			client.cookie = &http.Cookie{Name: "comet_session", Value: value}
+
+			id := client.set("visited", "yes")
+			if (id != record.ID) != test.expired {
+				t.Fatalf("expected expired %v, session %s was loaded as %s", test.expired, record.ID, id)
+			}
+
+			if _, user := client.get("user"); (user == "") != test.expired {
+				t.Fatalf("expected expired %v, got user %q", test.expired, user)
+			}
+		})
+	}
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	store := NewMemorySessionStore()
+	client := &sessionClient{config: SessionConfig{Secret: testSessionSecret, Store: store}}
+	previous := client.set("cart", "3 items")
+
+	var id string
+	client.do(func(s *SessionState) Response {
+		s.Regenerate()
+		s.Set("user", "alice")
+		id = s.ID()
+		return Ok("")
+	})
+
+	if id == previous {
+		t.Fatal("expected a new session ID")
+	}
+
+	if record, _ := store.Load(context.Background(), previous); record != nil {
+		t.Fatal("expected the previous session to be deleted from the store")
+	}
+
+	if got, cart := client.get("cart"); got != id || cart != "3 items" {
+		t.Fatalf("expected the values to be kept in session %s, got %q in %s", id, cart, got)
+	}
+
+	stale := &sessionClient{config: client.config}
+	codec, _ := newCookieCodec(testSessionSecret, nil)
+	value, _ := codec.encode("comet_session", []byte(previous))
+	stale.cookie = &http.Cookie{Name: "comet_session", Value: value}
+	if _, user := stale.get("user"); user != "" {
+		t.Fatal("expected the previous session ID to be unusable")
+	}
+}
+
+func TestSessionDestroy(t *testing.T) {
+	store := NewMemorySessionStore()
+	client := &sessionClient{config: SessionConfig{Secret: testSessionSecret, Store: store}}
+	id := client.set("user", "alice")
+
+	response := client.do(func(s *SessionState) Response {
+		s.Destroy()
+		return Ok("")
+	})
+
+	if client.cookie != nil || !strings.Contains(response.Headers.Get("Set-Cookie"), "Max-Age=0") {
+		t.Fatalf("expected the cookie to be expired, got %q", response.Headers.Get("Set-Cookie"))
+	}
+
+	if record, _ := store.Load(context.Background(), id); record != nil {
+		t.Fatal("expected the session to be deleted from the store")
+	}
+}
+
+func TestSessionNotPersistedUntilModified(t *testing.T) {
+	client := &sessionClient{config: SessionConfig{Secret: testSessionSecret}}
+	response := client.do(func(s *SessionState) Response {
+		s.Get("user")
+		return Ok("")
+	})
+
+	if response.Headers.Get("Set-Cookie") != "" {
+		t.Fatal("expected no cookie for an untouched new session")
+	}
+}
+
+func TestSessionFlashes(t *testing.T) {
+	client := &sessionClient{config: SessionConfig{Secret: testSessionSecret}}
+	client.do(func(s *SessionState) Response {
+		s.Flash("notice", "saved")
+		s.Flash("notice", "sent")
+		return Ok("")
+	})
+
+	for i, expected := range []int{2, 0} {
+		var flashes []interface{}
+		client.do(func(s *SessionState) Response {
+			flashes = s.Flashes("notice")
+			return Ok("")
+		})
+
+		if len(flashes) != expected {
+			t.Fatalf("read %d: expected %d flashes, got %v", i, expected, flashes)
+		}
+	}
+}
+
+func TestFileSessionStorePath(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		id    string
+		valid bool
+	}{
+		{name: "session id", id: newSessionID(), valid: true},
+		{name: "empty", id: ""},
+		{name: "traversal", id: "../../etc/passwd"},
+		{name: "separator", id: "ab/cd"},
+		{name: "uppercase", id: "ABCDEF"},
+		{name: "too long", id: strings.Repeat("a", 129)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record := &SessionRecord{ID: test.id, Values: map[string]interface{}{"user": "alice"}}
+			err := store.Save(context.Background(), record, time.Hour)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got %v", test.valid, err)
+			}
+
+			loaded, err := store.Load(context.Background(), test.id)
+			if err != nil || (loaded != nil) != test.valid {
+				t.Fatalf("expected loaded %v, got %v, %v", test.valid, loaded, err)
+			}
+
+			if err := store.Delete(context.Background(), test.id); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestFileSessionStoreExpiry(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := &SessionRecord{ID: newSessionID()}
+	if err := store.Save(context.Background(), record, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded, err := store.Load(context.Background(), record.ID); loaded != nil || err != nil {
+		t.Fatalf("expected the expired session to be removed, got %v, %v", loaded, err)
+	}
+}
+
+func TestMemorySessionStoreSweep(t *testing.T) {
+	store := NewMemorySessionStore()
+	ctx := context.Background()
+
+	for i := 0; i < 999; i++ {
+		store.Save(ctx, &SessionRecord{ID: newSessionID()}, -time.Second)
+	}
+
+	if len(store.sessions) != 999 {
+		t.Fatalf("expected the expired sessions to be kept until the sweep, got %d", len(store.sessions))
+	}
+
+	live := &SessionRecord{ID: newSessionID()}
+	store.Save(ctx, live, time.Hour)
+
+	if len(store.sessions) != 1 {
+		t.Fatalf("expected the sweep to remove the expired sessions, %d left", len(store.sessions))
+	}
+
+	if record, _ := store.Load(ctx, live.ID); record == nil {
+		t.Fatal("expected the live session to be kept")
+	}
+}
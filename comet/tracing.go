@@ -0,0 +1,55 @@
+package comet
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ramoncl001/go-comet/logs"
+	"github.com/ramoncl001/go-comet/tracing"
+)
+
+// Tracing returns a middleware starting a server span for every request,
+// named by its method and route pattern, with the tracer given or the
+// tracing default one when nil. Requests carrying a W3C traceparent header
+// continue the trace of the caller, and the trace ID is added to the logs.
+func Tracing(tracer *tracing.Tracer) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(r *Request) Response {
+			t := tracer
+			if t == nil {
+				t = tracing.Default()
+			}
+
+			ctx := r.Context()
+			if parent, ok := tracing.Extract(http.Header(r.Headers)); ok {
+				ctx = tracing.ContextWithRemote(ctx, parent)
+			}
+
+			route := r.Route()
+			name := r.Method
+			if route != "" {
+				name = fmt.Sprintf("%s %s", r.Method, route)
+			}
+
+			ctx, span := t.Start(ctx, name, tracing.KindServer)
+			defer span.End()
+
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.Url.Path)
+			if route != "" {
+				span.SetAttribute("http.route", route)
+			}
+
+			ctx = logs.WithFields(ctx, "trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+
+			response := next(r.WithContext(ctx))
+
+			span.SetAttribute("http.response.status_code", response.Status)
+			if response.Status >= 500 {
+				span.SetStatus(tracing.StatusError, http.StatusText(response.Status))
+			}
+
+			return response
+		}
+	}
+}
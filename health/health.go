@@ -0,0 +1,370 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+var (
+	// ErrDuplicateCheck is returned when two checks have the same name,
+	// or the name of an entry added by the checker itself
+	ErrDuplicateCheck = errors.New("health: duplicate check name")
+
+	errShuttingDown = errors.New("shutting down")
+)
+
+// names of the report entries added by the checker itself
+const (
+	shutdownEntry  = "shutdown"
+	containerEntry = "container"
+)
+
+// Check reports the health of a dependency, like a database, returning
+// an error when it is not available
+type Check interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string {
+	return c.name
+}
+
+func (c checkFunc) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+// CheckFunc returns a check calling the given function
+func CheckFunc(name string, check func(ctx context.Context) error) Check {
+	return checkFunc{name: name, check: check}
+}
+
+// Option configures a registered check
+type Option func(*options)
+
+// Configured is implemented by checks setting their own options,
+// like the checks resolved from the ioc container
+type Configured interface {
+	Options() []Option
+}
+
+type options struct {
+	timeout  time.Duration
+	cache    time.Duration
+	liveness bool
+}
+
+// Timeout fails the check when it takes longer, 5 seconds by default
+func Timeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// Cache reuses the result of the check for the given duration,
+// so frequent probes don't overload the dependency
+func Cache(duration time.Duration) Option {
+	return func(o *options) {
+		o.cache = duration
+	}
+}
+
+// Liveness runs the check in the liveness probe besides the readiness one.
+// Only checks whose failure requires restarting the process belong there.
+func Liveness() Option {
+	return func(o *options) {
+		o.liveness = true
+	}
+}
+
+type registeredCheck struct {
+	check   Check
+	options options
+
+	mu       sync.Mutex
+	cached   CheckResult
+	cachedAt time.Time
+}
+
+// CheckResult is the outcome of a check
+type CheckResult struct {
+	Status   Status  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+	Cached   bool    `json:"cached,omitempty"`
+}
+
+// Report aggregates the results of the checks, down when any of them is
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Checker runs the registered checks for the liveness and readiness probes
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []*registeredCheck
+	resolved     map[*ioc.Container]resolvedChecks
+	shuttingDown atomic.Bool
+}
+
+// resolvedChecks are the checks resolved from a container, kept so
+// their options and cached results apply across probes
+type resolvedChecks struct {
+	checks []*registeredCheck
+	err    error
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+var defaultChecker = NewChecker()
+
+// Default returns the checker used by the package level functions
+func Default() *Checker {
+	return defaultChecker
+}
+
+// Register adds a check to the readiness probe, or also to the liveness one
+// with the Liveness option. Check names must be unique, otherwise
+// ErrDuplicateCheck is returned.
+func (c *Checker) Register(check Check, opts ...Option) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// registered checks run along the checks of every container
+	taken := c.nameTaken(check.Name())
+	for _, resolved := range c.resolved {
+		taken = taken || containsCheck(resolved.checks, check.Name())
+	}
+
+	if taken {
+		return fmt.Errorf("%w: %s", ErrDuplicateCheck, check.Name())
+	}
+
+	c.checks = append(c.checks, &registeredCheck{check: check, options: newOptions(check, opts)})
+	return nil
+}
+
+// nameTaken reports whether a registered check or an entry added by
+// the checker uses the name, c.mu held
+func (c *Checker) nameTaken(name string) bool {
+	return name == shutdownEntry || name == containerEntry || containsCheck(c.checks, name)
+}
+
+// newOptions applies the options of the check, then the given ones
+func newOptions(check Check, opts []Option) options {
+	config := options{timeout: 5 * time.Second}
+	if configured, ok := check.(Configured); ok {
+		for _, option := range configured.Options() {
+			option(&config)
+		}
+	}
+
+	for _, option := range opts {
+		option(&config)
+	}
+
+	return config
+}
+
+// Register adds a check to the default checker
+func Register(check Check, opts ...Option) error {
+	return defaultChecker.Register(check, opts...)
+}
+
+// SetShuttingDown makes the readiness probe fail, so no new traffic
+// is routed to the process while it drains the active requests
+func (c *Checker) SetShuttingDown(shuttingDown bool) {
+	c.shuttingDown.Store(shuttingDown)
+}
+
+// Liveness runs the checks registered with the Liveness option, including
+// the container checks setting it. Failing to resolve the container checks
+// is only reported by the readiness probe.
+func (c *Checker) Liveness(ctx context.Context) Report {
+	c.mu.RLock()
+	registered := append([]*registeredCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	registered = append(registered, c.containerChecks(ctx).checks...)
+
+	checks := make([]*registeredCheck, 0, len(registered))
+	for _, check := range registered {
+		if check.options.liveness {
+			checks = append(checks, check)
+		}
+	}
+
+	return run(ctx, checks)
+}
+
+// Readiness runs every registered check, and the checks registered in the
+// ioc container of ctx as health.Check, failing while shutting down.
+// Container checks are resolved once, on the first probe and outside any
+// request scope; they set their options implementing Configured.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]*registeredCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	resolved := c.containerChecks(ctx)
+	checks = append(checks, resolved.checks...)
+
+	report := run(ctx, checks)
+	if resolved.err != nil {
+		report.Status = StatusDown
+		report.Checks[containerEntry] = CheckResult{Status: StatusDown, Error: resolved.err.Error()}
+	}
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusDown
+		report.Checks[shutdownEntry] = CheckResult{Status: StatusDown, Error: errShuttingDown.Error()}
+	}
+
+	return report
+}
+
+// containerChecks returns the checks of the container of ctx, resolving
+// them on the first call. Resolution errors are retried on the next call,
+// while checks whose name is taken are left out and reported every time.
+// They are resolved outside the request scope of ctx, as they outlive it.
+func (c *Checker) containerChecks(ctx context.Context) resolvedChecks {
+	container := ioc.FromContext(ctx)
+
+	c.mu.RLock()
+	resolved, ok := c.resolved[container]
+	c.mu.RUnlock()
+
+	if ok {
+		return resolved
+	}
+
+	checks, err := ioc.ResolveAll[Check](ioc.WithContainer(context.Background(), container))
+	if err != nil {
+		return resolvedChecks{err: err}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// another probe may have resolved them meanwhile
+	if resolved, ok := c.resolved[container]; ok {
+		return resolved
+	}
+
+	if c.resolved == nil {
+		c.resolved = make(map[*ioc.Container]resolvedChecks)
+	}
+
+	var errs []error
+	for _, check := range checks {
+		if c.nameTaken(check.Name()) || containsCheck(resolved.checks, check.Name()) {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrDuplicateCheck, check.Name()))
+			continue
+		}
+		resolved.checks = append(resolved.checks, &registeredCheck{check: check, options: newOptions(check, nil)})
+	}
+
+	resolved.err = errors.Join(errs...)
+	c.resolved[container] = resolved
+	return resolved
+}
+
+func containsCheck(checks []*registeredCheck, name string) bool {
+	for _, check := range checks {
+		if check.check.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs the checks concurrently
+func run(ctx context.Context, checks []*registeredCheck) Report {
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *registeredCheck) {
+			defer wg.Done()
+			results[i] = check.run(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	for i, check := range checks {
+		report.Checks[check.check.Name()] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+func (r *registeredCheck) run(ctx context.Context) CheckResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.options.cache > 0 && !r.cachedAt.IsZero() && time.Since(r.cachedAt) < r.options.cache {
+		result := r.cached
+		result.Cached = true
+		return result
+	}
+
+	start := time.Now()
+	result := CheckResult{Status: StatusUp}
+	if err := runWithTimeout(ctx, r.check, r.options.timeout); err != nil {
+		result = CheckResult{Status: StatusDown, Error: err.Error()}
+	}
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000
+
+	r.cached = result
+	r.cachedAt = time.Now()
+	return result
+}
+
+// runWithTimeout returns once the check completes or times out, even
+// when the check does not honor the cancellation of its context
+func runWithTimeout(ctx context.Context, check Check, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
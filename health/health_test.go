@@ -0,0 +1,179 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ramoncl001/go-comet/health"
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type brokerCheck struct {
+	calls *int32
+}
+
+func (brokerCheck) Name() string { return "broker" }
+
+func (c brokerCheck) Check(context.Context) error {
+	atomic.AddInt32(c.calls, 1)
+	return nil
+}
+
+func (brokerCheck) Options() []health.Option {
+	return []health.Option{health.Cache(time.Minute)}
+}
+
+func TestReadinessContainerChecks(t *testing.T) {
+	var built, calls int32
+	c := ioc.New()
+	c.RegisterKeyedTransient(ioc.TypeOf[health.Check](), func() health.Check {
+		atomic.AddInt32(&built, 1)
+		return brokerCheck{calls: &calls}
+	}, "broker")
+
+	checker := health.NewChecker()
+	ctx := ioc.WithContainer(context.Background(), c)
+
+	for i := 0; i < 3; i++ {
+		report := checker.Readiness(ctx)
+		if report.Status != health.StatusUp || report.Checks["broker"].Status != health.StatusUp {
+			t.Fatalf("unexpected report %+v", report)
+		}
+	}
+
+	if built != 1 {
+		t.Fatalf("expected the check to be resolved once, was built %d times", built)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the cache option of the check to apply, ran %d times", calls)
+	}
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	checker := health.NewChecker()
+	err := checker.Register(health.CheckFunc("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), health.Timeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := checker.Readiness(ioc.WithContainer(context.Background(), ioc.New()))
+	if result := report.Checks["slow"]; result.Status != health.StatusDown || result.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected the check to time out, got %+v", result)
+	}
+}
+
+func TestDuplicateCheckNames(t *testing.T) {
+	checker := health.NewChecker()
+	ok := func(context.Context) error { return nil }
+
+	if err := checker.Register(health.CheckFunc("broker", ok)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checker.Register(health.CheckFunc("broker", ok)); !errors.Is(err, health.ErrDuplicateCheck) {
+		t.Fatalf("expected ErrDuplicateCheck, got %v", err)
+	}
+
+	if err := checker.Register(health.CheckFunc("shutdown", ok)); !errors.Is(err, health.ErrDuplicateCheck) {
+		t.Fatalf("expected reserved names to be rejected, got %v", err)
+	}
+
+	var calls int32
+	c := ioc.New()
+	c.RegisterKeyedSingleton(ioc.TypeOf[health.Check](), brokerCheck{calls: &calls}, "broker")
+
+	report := checker.Readiness(ioc.WithContainer(context.Background(), c))
+	if report.Status != health.StatusDown || report.Checks["container"].Status != health.StatusDown {
+		t.Fatalf("expected the duplicate container check to be reported, got %+v", report)
+	}
+
+	if calls != 0 {
+		t.Fatal("expected the duplicate container check not to run")
+	}
+}
+
+func TestReadinessShuttingDown(t *testing.T) {
+	checker := health.NewChecker()
+	ctx := ioc.WithContainer(context.Background(), ioc.New())
+
+	if report := checker.Readiness(ctx); report.Status != health.StatusUp {
+		t.Fatalf("expected the checker to be ready, got %+v", report)
+	}
+
+	checker.SetShuttingDown(true)
+	if report := checker.Readiness(ctx); report.Status != health.StatusDown {
+		t.Fatalf("expected readiness to fail while shutting down, got %+v", report)
+	}
+
+	if report := checker.Liveness(ctx); report.Status != health.StatusUp {
+		t.Fatalf("expected liveness to be unaffected, got %+v", report)
+	}
+}
+
+type connectionCheck struct {
+	disposed bool
+}
+
+func (*connectionCheck) Name() string { return "connection" }
+
+func (c *connectionCheck) Check(context.Context) error {
+	if c.disposed {
+		return errors.New("used after disposal")
+	}
+	return nil
+}
+
+func (c *connectionCheck) Dispose() error {
+	c.disposed = true
+	return nil
+}
+
+func TestReadinessScopedContainerCheck(t *testing.T) {
+	c := ioc.New()
+	c.RegisterKeyedScoped(ioc.TypeOf[health.Check](), func() health.Check {
+		return &connectionCheck{}
+	}, "connection")
+
+	checker := health.NewChecker()
+	for i := 0; i < 2; i++ {
+		ctx, scope := ioc.NewScope(ioc.WithContainer(context.Background(), c))
+		report := checker.Readiness(ctx)
+		scope.Close()
+
+		if result := report.Checks["connection"]; result.Status != health.StatusUp {
+			t.Fatalf("probe %d: expected the check to outlive the request scope, got %+v", i, result)
+		}
+	}
+}
+
+type diskCheck struct{}
+
+func (diskCheck) Name() string                { return "disk" }
+func (diskCheck) Check(context.Context) error { return errors.New("disk full") }
+
+func (diskCheck) Options() []health.Option {
+	return []health.Option{health.Liveness()}
+}
+
+func TestLivenessContainerChecks(t *testing.T) {
+	var calls int32
+	c := ioc.New()
+	c.RegisterKeyedSingleton(ioc.TypeOf[health.Check](), health.Check(diskCheck{}), "disk")
+	c.RegisterKeyedSingleton(ioc.TypeOf[health.Check](), health.Check(brokerCheck{calls: &calls}), "broker")
+
+	report := health.NewChecker().Liveness(ioc.WithContainer(context.Background(), c))
+	if report.Status != health.StatusDown || report.Checks["disk"].Status != health.StatusDown {
+		t.Fatalf("expected the liveness container check to run, got %+v", report)
+	}
+
+	if _, ok := report.Checks["broker"]; ok || calls != 0 {
+		t.Fatal("expected the readiness only container check to be left out")
+	}
+}
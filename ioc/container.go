@@ -0,0 +1,207 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Container stores service registrations and resolves them. The package
+// level Register and Resolve functions operate on the Default container.
+type Container struct {
+	mu                sync.RWMutex
+	parent            *Container
+	transientServices map[reflect.Type]map[interface{}]service
+	singletonServices map[reflect.Type]map[interface{}]service
+	scopedServices    map[reflect.Type]map[interface{}]service
+	disposables       []interface{}
+	eager             []serviceKey
+	closed            bool
+}
+
+// New creates an empty container
+func New() *Container {
+	return &Container{
+		transientServices: make(map[reflect.Type]map[interface{}]service),
+		singletonServices: make(map[reflect.Type]map[interface{}]service),
+		scopedServices:    make(map[reflect.Type]map[interface{}]service),
+		disposables:       make([]interface{}, 0),
+	}
+}
+
+// NewChild creates a container inheriting the registrations of c. Services
+// registered in the child override the inherited ones without modifying c.
+// Inherited singletons are shared with c, while inherited transient and
+// scoped services are built by the child, seeing its overrides.
+func (c *Container) NewChild() *Container {
+	child := New()
+	child.parent = c
+	return child
+}
+
+var defaultContainer = New()
+
+// sequence orders registrations across containers, so the ones
+// of a child always come after the ones of its parent
+var sequence uint64
+
+// Default returns the container used by the package level functions
+func Default() *Container {
+	return defaultContainer
+}
+
+type containerKey struct{}
+
+// WithContainer returns a copy of ctx bound to the given container,
+// so Resolve calls using that context are served by it
+func WithContainer(ctx context.Context, c *Container) context.Context {
+	return context.WithValue(ctx, containerKey{}, c)
+}
+
+// FromContext returns the container bound to ctx, or the Default container
+func FromContext(ctx context.Context) *Container {
+	if ctx != nil {
+		if c, ok := ctx.Value(containerKey{}).(*Container); ok && c != nil {
+			return c
+		}
+	}
+	return defaultContainer
+}
+
+// TypeOf returns the reflect.Type used to register and resolve T
+func TypeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// register stores the service replacing any registration of t under
+// the same key, whatever its lifetime
+func (c *Container) register(t reflect.Type, key interface{}, s service) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s.seq = atomic.AddUint64(&sequence, 1)
+	c.store(t, key, s)
+}
+
+// store saves the service in the map of its lifetime, replacing any
+// registration of t under the same key, the lock must be held
+func (c *Container) store(t reflect.Type, key interface{}, s service) {
+	c.remove(t, key)
+
+	services := c.lifetimes()[s.sType]
+	if _, ok := services[t]; !ok {
+		services[t] = make(map[interface{}]service)
+	}
+
+	services[t][key] = s
+}
+
+// remove deletes the registration of t under key, the lock must be held
+func (c *Container) remove(t reflect.Type, key interface{}) {
+	for _, services := range c.lifetimes() {
+		delete(services[t], key)
+	}
+}
+
+// lifetimes returns the service maps indexed by serviceType
+func (c *Container) lifetimes() []map[reflect.Type]map[interface{}]service {
+	return []map[reflect.Type]map[interface{}]service{c.transientServices, c.singletonServices, c.scopedServices}
+}
+
+// local returns the registration of t under key made in c itself,
+// the lock must be held
+func (c *Container) local(t reflect.Type, key interface{}) (service, bool) {
+	for _, services := range c.lifetimes() {
+		if s, ok := services[t][key]; ok {
+			return s, true
+		}
+	}
+	return service{}, false
+}
+
+// find returns the registration of t under key, looking into the parent
+// containers when c has none, along with the container owning it
+func (c *Container) find(t reflect.Type, key interface{}) (service, *Container, bool) {
+	c.mu.RLock()
+	s, ok := c.local(t, key)
+	c.mu.RUnlock()
+
+	if ok {
+		return s, c, true
+	}
+
+	if c.parent != nil {
+		return c.parent.find(t, key)
+	}
+
+	return service{}, nil, false
+}
+
+// servicesOf returns every registration of t by key, including the
+// inherited ones not overridden by c
+func (c *Container) servicesOf(t reflect.Type) map[interface{}]service {
+	result := make(map[interface{}]service)
+	if c.parent != nil {
+		result = c.parent.servicesOf(t)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, services := range c.lifetimes() {
+		for key, s := range services[t] {
+			result[key] = s
+		}
+	}
+
+	return result
+}
+
+// keys returns the keys registered for t in registration order
+func (c *Container) keys(t reflect.Type) []interface{} {
+	services := c.servicesOf(t)
+
+	result := make([]interface{}, 0, len(services))
+	for key := range services {
+		result = append(result, key)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return services[result[i]].seq < services[result[j]].seq
+	})
+
+	return result
+}
+
+// findKey returns the key registered for t matching the given name, either
+// being the name itself or formatting as the name, as keys come from tags
+func (c *Container) findKey(t reflect.Type, name string) (interface{}, bool) {
+	keys := c.keys(t)
+	for _, key := range keys {
+		if key == name {
+			return key, true
+		}
+	}
+
+	for _, key := range keys {
+		if fmt.Sprint(key) == name {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Container) isRegistered(t reflect.Type, key interface{}) bool {
+	_, _, ok := c.find(t, key)
+	return ok
+}
+
+func (c *Container) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closed
+}
@@ -0,0 +1,96 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Decorate wraps the un-keyed registration of T, whatever its lifetime, with
+// a decorator receiving the inner service followed by its own dependencies
+//
+//	ioc.Decorate[Repository](func(inner Repository, cache Cache) Repository {
+//		return &cachedRepository{inner: inner, cache: cache}
+//	})
+//
+// Decorators are applied in registration order, so the first one registered
+// is the closest to the original service. Singletons must be decorated before
+// their first resolve, otherwise ErrSingletonBuilt is returned and the
+// registration is left unchanged.
+func Decorate[T any](decorator interface{}) error {
+	return defaultContainer.Decorate(TypeOf[T](), decorator)
+}
+
+func DecorateKeyed[T any](decorator interface{}, key interface{}) error {
+	return defaultContainer.DecorateKeyed(TypeOf[T](), decorator, key)
+}
+
+func (c *Container) Decorate(t reflect.Type, decorator interface{}) error {
+	return c.DecorateKeyed(t, decorator, 0)
+}
+
+func (c *Container) DecorateKeyed(t reflect.Type, decorator interface{}, key interface{}) error {
+	dependency := Dependency{Type: t, Key: key}
+	if err := validateDecorator(t, decorator); err != nil {
+		return fmt.Errorf("ioc: cannot decorate %s: %w", dependency, err)
+	}
+
+	s, owner, ok := c.find(t, key)
+	if !ok {
+		return fmt.Errorf("ioc: cannot decorate %s: %w", dependency, ErrDependencyNotFound)
+	}
+
+	// the instance already handed out would stay undecorated, and building
+	// a decorated one would break the single instance guarantee
+	if s.sType == singleton && owner == c && s.built() {
+		return fmt.Errorf("ioc: cannot decorate %s: %w", dependency, ErrSingletonBuilt)
+	}
+
+	s.decorators = append(append([]interface{}(nil), s.decorators...), decorator)
+
+	// decorated instances must be built once, like singleton factories.
+	// Inherited singletons are decorated in a copy owned by the child.
+	if s.sType == singleton && (s.lazy == nil || owner != c) {
+		s.lazy = &lazyInstance{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(t, key, s)
+	return nil
+}
+
+func validateDecorator(t reflect.Type, decorator interface{}) error {
+	tp := reflect.TypeOf(decorator)
+	if tp == nil || tp.Kind() != reflect.Func {
+		return fmt.Errorf("%w: decorator %v is not a function", errInvalidProvider, tp)
+	}
+
+	if err := validateProvider(tp); err != nil {
+		return err
+	}
+
+	if tp.NumIn() == 0 || !t.AssignableTo(tp.In(0)) || !tp.Out(0).AssignableTo(t) {
+		return fmt.Errorf("%w: decorator %s must receive and return %s", errInvalidProvider, tp, t)
+	}
+
+	return nil
+}
+
+// decorate applies the decorators of the service to the built value
+func (c *Container) decorate(ctx context.Context, s service, value interface{}) (interface{}, error) {
+	for _, decorator := range s.decorators {
+		inner, err := argumentValue(value, reflect.TypeOf(decorator).In(0))
+		if err != nil {
+			return nil, err
+		}
+
+		value, err = c.callWith(ctx, decorator, inner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
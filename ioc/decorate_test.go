@@ -0,0 +1,114 @@
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type greeter interface {
+	Greet() string
+}
+
+type plainGreeter struct{}
+
+func (plainGreeter) Greet() string { return "hello" }
+
+type loudGreeter struct{ inner greeter }
+
+func (g loudGreeter) Greet() string { return g.inner.Greet() + "!" }
+
+func decorateLoud(inner greeter) greeter {
+	return loudGreeter{inner: inner}
+}
+
+func TestDecorateSingleton(t *testing.T) {
+	c := ioc.New()
+	c.RegisterSingletonFactory(ioc.TypeOf[greeter](), func() greeter { return plainGreeter{} })
+
+	if err := c.Decorate(ioc.TypeOf[greeter](), decorateLoud); err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := ioc.Resolve[greeter](ioc.WithContainer(context.Background(), c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instance.Greet() != "hello!" {
+		t.Fatalf("expected the decorated singleton, got %q", instance.Greet())
+	}
+}
+
+func TestDecorateBuiltSingleton(t *testing.T) {
+	c := ioc.New()
+	c.RegisterSingletonFactory(ioc.TypeOf[greeter](), func() greeter { return plainGreeter{} })
+
+	ctx := ioc.WithContainer(context.Background(), c)
+	before, err := ioc.Resolve[greeter](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Decorate(ioc.TypeOf[greeter](), decorateLoud); !errors.Is(err, ioc.ErrSingletonBuilt) {
+		t.Fatalf("expected ErrSingletonBuilt, got %v", err)
+	}
+
+	after, err := ioc.Resolve[greeter](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before != after || after.Greet() != "hello" {
+		t.Fatalf("expected the registration to be left unchanged, got %q", after.Greet())
+	}
+}
+
+func TestDecorateResolvedInstanceSingleton(t *testing.T) {
+	c := ioc.New()
+	c.RegisterSingleton(ioc.TypeOf[greeter](), greeter(plainGreeter{}))
+
+	ctx := ioc.WithContainer(context.Background(), c)
+	if _, err := ioc.Resolve[greeter](ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Decorate(ioc.TypeOf[greeter](), decorateLoud); !errors.Is(err, ioc.ErrSingletonBuilt) {
+		t.Fatalf("expected ErrSingletonBuilt, got %v", err)
+	}
+
+	after, err := ioc.Resolve[greeter](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after.Greet() != "hello" {
+		t.Fatalf("expected the registration to be left unchanged, got %q", after.Greet())
+	}
+}
+
+func TestDecorateInstanceSingleton(t *testing.T) {
+	c := ioc.New()
+	c.RegisterSingleton(ioc.TypeOf[greeter](), greeter(plainGreeter{}))
+
+	if err := c.Decorate(ioc.TypeOf[greeter](), decorateLoud); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ioc.WithContainer(context.Background(), c)
+	first, err := ioc.Resolve[greeter](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := ioc.Resolve[greeter](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Greet() != "hello!" || first != second {
+		t.Fatalf("expected the same decorated instance, got %q and %q", first.Greet(), second.Greet())
+	}
+}
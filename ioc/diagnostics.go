@@ -0,0 +1,174 @@
+package ioc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+func (t serviceType) String() string {
+	switch t {
+	case singleton:
+		return "singleton"
+	case scoped:
+		return "scoped"
+	default:
+		return "transient"
+	}
+}
+
+// Registration describes a registered service, as listed by Registrations
+type Registration struct {
+	Service  Dependency `json:"service"`
+	Lifetime string     `json:"lifetime"`
+	// Provider is the signature of the provider, or the type of the
+	// instance for singletons registered with an already built value
+	Provider     string       `json:"provider"`
+	Decorators   []string     `json:"decorators,omitempty"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// MarshalJSON writes the dependency with its type name and key
+func (d Dependency) MarshalJSON() ([]byte, error) {
+	value := struct {
+		Type string      `json:"type"`
+		Key  interface{} `json:"key,omitempty"`
+	}{Type: typeName(d.Type)}
+
+	if d.Key != 0 && d.Key != nil {
+		value.Key = fmt.Sprint(d.Key)
+	}
+
+	return json.Marshal(value)
+}
+
+// Registrations lists the services of the container, including the ones
+// inherited from its parents, in registration order
+func (c *Container) Registrations() []Registration {
+	registrations := c.registrations()
+
+	result := make([]Registration, 0, len(registrations))
+	for _, r := range registrations {
+		provider := fmt.Sprintf("instance of %T", r.provider)
+		if r.factory {
+			provider = typeName(reflect.TypeOf(r.provider))
+		}
+
+		decorators := make([]string, 0, len(r.decorators))
+		for _, decorator := range r.decorators {
+			decorators = append(decorators, typeName(reflect.TypeOf(decorator)))
+		}
+
+		result = append(result, Registration{
+			Service:      r.dependency,
+			Lifetime:     r.lifetime.String(),
+			Provider:     provider,
+			Decorators:   decorators,
+			Dependencies: c.dependencies(r),
+		})
+	}
+
+	return result
+}
+
+// Registrations lists the services of the default container
+func Registrations() []Registration {
+	return defaultContainer.Registrations()
+}
+
+// graphNode is a service of the dependency graph. Dependencies
+// that are not registered are added with the "missing" lifetime.
+type graphNode struct {
+	ID       string     `json:"id"`
+	Service  Dependency `json:"service"`
+	Lifetime string     `json:"lifetime"`
+	Provider string     `json:"provider,omitempty"`
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type graph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+func (c *Container) graph() graph {
+	registrations := c.Registrations()
+
+	result := graph{
+		Nodes: make([]graphNode, 0, len(registrations)),
+		Edges: make([]graphEdge, 0),
+	}
+
+	nodes := make(map[Dependency]bool, len(registrations))
+	for _, r := range registrations {
+		nodes[r.Service] = true
+		result.Nodes = append(result.Nodes, graphNode{
+			ID:       r.Service.String(),
+			Service:  r.Service,
+			Lifetime: r.Lifetime,
+			Provider: r.Provider,
+		})
+	}
+
+	for _, r := range registrations {
+		for _, dependency := range r.Dependencies {
+			if !nodes[dependency] {
+				nodes[dependency] = true
+				result.Nodes = append(result.Nodes, graphNode{
+					ID:       dependency.String(),
+					Service:  dependency,
+					Lifetime: "missing",
+				})
+			}
+
+			result.Edges = append(result.Edges, graphEdge{
+				From: r.Service.String(),
+				To:   dependency.String(),
+			})
+		}
+	}
+
+	return result
+}
+
+// WriteJSON writes the dependency graph of the container as JSON, with the
+// registered services as nodes and an edge from every service to each of
+// the services its provider depends on
+func (c *Container) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c.graph())
+}
+
+// WriteDOT writes the dependency graph of the container in the Graphviz
+// DOT language, to be rendered with `dot -Tsvg`
+func (c *Container) WriteDOT(w io.Writer) error {
+	g := c.graph()
+
+	var b strings.Builder
+	b.WriteString("digraph ioc {\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		style := ""
+		if node.Lifetime == "missing" {
+			style = ", style=dashed, color=red"
+		}
+		fmt.Fprintf(&b, "  %q [label=%q%s];\n", node.ID, node.ID+"\n"+node.Lifetime, style)
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,84 @@
+package ioc
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// Disposable is implemented by services releasing resources when their
+// lifetime ends. Services implementing io.Closer are disposed as well.
+type Disposable interface {
+	Dispose() error
+}
+
+func isDisposable(instance interface{}) bool {
+	switch instance.(type) {
+	case Disposable, io.Closer:
+		return true
+	}
+	return false
+}
+
+func dispose(instance interface{}) error {
+	switch value := instance.(type) {
+	case Disposable:
+		return value.Dispose()
+	case io.Closer:
+		return value.Close()
+	}
+	return nil
+}
+
+// disposeAll disposes the instances in reverse order, skipping the ones
+// tracked more than once, and joins the returned errors
+func disposeAll(instances []interface{}) error {
+	disposed := make(map[interface{}]bool)
+	errs := make([]error, 0)
+
+	for i := len(instances) - 1; i >= 0; i-- {
+		instance := instances[i]
+
+		if reflect.TypeOf(instance).Comparable() {
+			if disposed[instance] {
+				continue
+			}
+			disposed[instance] = true
+		}
+
+		if err := dispose(instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// track records a singleton to be disposed when the container is closed
+func (c *Container) track(instance interface{}) {
+	if !isDisposable(instance) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.disposables = append(c.disposables, instance)
+}
+
+// Close disposes the singletons of the container in reverse creation order.
+// Resolving from a closed container fails.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	instances := c.disposables
+	c.disposables = nil
+	c.closed = true
+	c.mu.Unlock()
+
+	return disposeAll(instances)
+}
+
+// Close disposes the singletons of the default container
+func Close() error {
+	return defaultContainer.Close()
+}
@@ -0,0 +1,110 @@
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrDependencyNotFound is returned when no service is registered for the
+	// requested type and key, or for one of the dependencies of its provider
+	ErrDependencyNotFound = errors.New("dependency not found")
+
+	// ErrSingletonBuilt is returned by Decorate when the singleton has
+	// already been resolved, as the instances handed out can't be decorated
+	ErrSingletonBuilt = errors.New("singleton already built")
+
+	errScopeClosed     = errors.New("scope is closed")
+	errContainerClosed = errors.New("container is closed")
+	errInvalidProvider = errors.New("invalid provider")
+)
+
+// Dependency identifies a registration by its type and key
+type Dependency struct {
+	Type reflect.Type
+	Key  interface{}
+}
+
+func (d Dependency) String() string {
+	if d.Key == 0 || d.Key == nil {
+		return typeName(d.Type)
+	}
+	return fmt.Sprintf("%s[key=%v]", typeName(d.Type), d.Key)
+}
+
+// ResolutionError reports a failed resolution with the chain of
+// dependencies that led to the failing one
+type ResolutionError struct {
+	// Path starts with the requested service and ends with the one that failed
+	Path []Dependency
+	Err  error
+}
+
+func (e *ResolutionError) Error() string {
+	parts := make([]string, len(e.Path))
+	for i, dependency := range e.Path {
+		parts[i] = dependency.String()
+	}
+
+	return fmt.Sprintf("ioc: cannot resolve %s: %s: %v", e.Path[0], strings.Join(parts, " -> "), e.Err)
+}
+
+func (e *ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// Requested returns the service whose resolution failed
+func (e *ResolutionError) Requested() Dependency {
+	return e.Path[0]
+}
+
+type pathContextKey struct{}
+
+// resolutionPath returns the dependencies being resolved in ctx,
+// from the outermost to the innermost
+func resolutionPath(ctx context.Context) []Dependency {
+	path, _ := ctx.Value(pathContextKey{}).([]Dependency)
+	return path
+}
+
+func withDependency(ctx context.Context, dependency Dependency) (context.Context, []Dependency) {
+	parent := resolutionPath(ctx)
+	path := make([]Dependency, len(parent)+1)
+	copy(path, parent)
+	path[len(parent)] = dependency
+
+	return context.WithValue(ctx, pathContextKey{}, path), path
+}
+
+// resolutionError wraps err with the resolution path, keeping the
+// innermost ResolutionError when err already is one
+func resolutionError(path []Dependency, err error) error {
+	var resolution *ResolutionError
+	if errors.As(err, &resolution) {
+		return err
+	}
+
+	return &ResolutionError{
+		Path: path,
+		Err:  err,
+	}
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "<nil>"
+	}
+	return t.String()
+}
+
+func containsDependency(path []Dependency, dependency Dependency) bool {
+	for _, d := range path {
+		if d == dependency {
+			return true
+		}
+	}
+	return false
+}
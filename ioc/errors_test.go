@@ -0,0 +1,129 @@
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type (
+	orderService    struct{ repository *orderRepository }
+	orderRepository struct{ db *database }
+	database        struct{}
+	ledger          struct{}
+)
+
+var errConnectionRefused = errors.New("connection refused")
+
+func newOrderService(repository *orderRepository) *orderService {
+	return &orderService{repository: repository}
+}
+
+func newOrderRepository(db *database) *orderRepository {
+	return &orderRepository{db: db}
+}
+
+func TestResolutionError(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(c *ioc.Container)
+		err      error
+		path     []string
+		message  string
+	}{
+		{
+			name: "provider error",
+			register: func(c *ioc.Container) {
+				c.RegisterTransient(ioc.TypeOf[*database](), func() (*database, error) {
+					return nil, errConnectionRefused
+				})
+			},
+			err:     errConnectionRefused,
+			path:    []string{"*ioc_test.orderService", "*ioc_test.orderRepository", "*ioc_test.database"},
+			message: "ioc: cannot resolve *ioc_test.orderService: *ioc_test.orderService -> *ioc_test.orderRepository -> *ioc_test.database: connection refused",
+		},
+		{
+			name:     "missing dependency",
+			register: func(c *ioc.Container) {},
+			err:      ioc.ErrDependencyNotFound,
+			path:     []string{"*ioc_test.orderService", "*ioc_test.orderRepository", "*ioc_test.database"},
+			message:  "ioc: cannot resolve *ioc_test.orderService: *ioc_test.orderService -> *ioc_test.orderRepository -> *ioc_test.database: dependency not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := ioc.New()
+			c.RegisterTransient(ioc.TypeOf[*orderService](), newOrderService)
+			c.RegisterTransient(ioc.TypeOf[*orderRepository](), newOrderRepository)
+			test.register(c)
+
+			_, err := ioc.Resolve[*orderService](ioc.WithContainer(context.Background(), c))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+
+			var resolution *ioc.ResolutionError
+			if !errors.As(err, &resolution) {
+				t.Fatalf("expected a *ioc.ResolutionError, got %T", err)
+			}
+
+			if len(resolution.Path) != len(test.path) {
+				t.Fatalf("expected the path %v, got %v", test.path, resolution.Path)
+			}
+
+			for i, dependency := range resolution.Path {
+				if dependency.String() != test.path[i] {
+					t.Fatalf("expected the path %v, got %v", test.path, resolution.Path)
+				}
+			}
+
+			if resolution.Requested().String() != test.path[0] {
+				t.Fatalf("expected the requested service %s, got %s", test.path[0], resolution.Requested())
+			}
+
+			if err.Error() != test.message {
+				t.Fatalf("unexpected message %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestProviderReturningError(t *testing.T) {
+	c := ioc.New()
+	ctx := ioc.WithContainer(context.Background(), c)
+
+	var fail error
+	c.RegisterSingletonFactory(ioc.TypeOf[*ledger](), func() (*ledger, error) {
+		if fail != nil {
+			return nil, fail
+		}
+		return &ledger{}, nil
+	})
+
+	fail = errConnectionRefused
+	if _, err := ioc.Resolve[*ledger](ctx); !errors.Is(err, errConnectionRefused) {
+		t.Fatalf("expected the provider error, got %v", err)
+	}
+
+	// failed constructions are not cached
+	fail = nil
+	instance, err := ioc.Resolve[*ledger](ctx)
+	if err != nil || instance == nil {
+		t.Fatalf("expected the singleton to be built on the next resolve, got %v, %v", instance, err)
+	}
+}
+
+func TestKeyedDependencyInPath(t *testing.T) {
+	c := ioc.New()
+	c.RegisterKeyedTransient(ioc.TypeOf[*database](), func() (*database, error) {
+		return nil, errConnectionRefused
+	}, "replica")
+
+	_, err := ioc.ResolveKeyed[*database](ioc.WithContainer(context.Background(), c), "replica")
+	if err == nil || err.Error() != "ioc: cannot resolve *ioc_test.database[key=replica]: *ioc_test.database[key=replica]: connection refused" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -2,15 +2,15 @@ package ioc
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"reflect"
-	"sync"
-)
+	"sync/atomic"
 
-var (
-	errDependencyNotFound = errors.New("dependency not found")
+	"github.com/ramoncl001/go-comet/tracing"
 )
 
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 type serviceType int
 
 const (
@@ -22,6 +22,23 @@ const (
 type service struct {
 	value interface{}
 	sType serviceType
+	lazy  *lazyInstance
+	out   *output
+	seq   uint64
+
+	// instance is set for singletons registered with an already built value,
+	// resolved once a resolve returned it
+	instance   bool
+	resolved   *atomic.Bool
+	decorators []interface{}
+}
+
+// built reports whether a singleton was already handed out by a resolve
+func (s service) built() bool {
+	if s.lazy != nil {
+		return s.lazy.built()
+	}
+	return s.resolved != nil && s.resolved.Load()
 }
 
 func newService[T any](value T, t serviceType) service {
@@ -31,60 +48,156 @@ func newService[T any](value T, t serviceType) service {
 	}
 }
 
-var transientServices = make(map[reflect.Type]map[interface{}]service)
-var singletonServices = make(map[reflect.Type]map[interface{}]service)
-var scopedServices = make(map[reflect.Type]map[interface{}]service)
-
 func Resolve[T any](ctx context.Context) (T, error) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
-	result, err := resolve(ctx, tp)
+	return ResolveKeyed[T](ctx, 0)
+}
+
+func ResolveKeyed[T any](ctx context.Context, key interface{}) (T, error) {
+	tp := TypeOf[T]()
+	result, err := FromContext(ctx).ResolveKeyed(ctx, tp, key)
 	if err != nil {
 		return *new(T), err
 	}
 
 	if result == nil {
-		return *new(T), errDependencyNotFound
+		return *new(T), resolutionError([]Dependency{{Type: tp, Key: key}}, ErrDependencyNotFound)
+	}
+
+	value, ok := result.(T)
+	if !ok {
+		err := fmt.Errorf("%w: provider returned %T", errInvalidProvider, result)
+		return *new(T), resolutionError([]Dependency{{Type: tp, Key: key}}, err)
 	}
 
-	return result.(T), nil
+	return value, nil
 }
 
-func ResolveKeyed[T any](ctx context.Context, key interface{}) (T, error) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
-	result, err := resolveKeyed(ctx, tp, key)
+// Resolve returns an instance of the service registered for t
+func (c *Container) Resolve(ctx context.Context, t reflect.Type) (interface{}, error) {
+	return c.ResolveKeyed(ctx, t, 0)
+}
+
+// ResolveKeyed returns an instance of the service registered for t under key.
+// Failures are reported as a *ResolutionError.
+func (c *Container) ResolveKeyed(ctx context.Context, t reflect.Type, key interface{}) (interface{}, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	dependency := Dependency{Type: t, Key: key}
+	if containsDependency(resolutionPath(ctx), dependency) {
+		_, path := withDependency(ctx, dependency)
+		return nil, resolutionError(path, ErrCircularDependency)
+	}
+
+	ctx, path := withDependency(ctx, dependency)
+
+	if c.isClosed() {
+		return nil, resolutionError(path, errContainerClosed)
+	}
+
+	// resolutions are traced as part of an existing trace, like a request
+	var span *tracing.Span
+	if tracing.SpanFromContext(ctx) != nil {
+		ctx, span = tracing.Start(ctx, "ioc.resolve "+dependency.String(), tracing.KindInternal)
+		defer span.End()
+	}
+
+	result, err := c.resolveKeyed(ctx, t, key)
 	if err != nil {
-		return *new(T), err
+		if span != nil {
+			span.RecordError(err)
+		}
+		return nil, resolutionError(path, err)
 	}
 
-	if result == nil {
-		return *new(T), errDependencyNotFound
+	return result, nil
+}
+
+func (c *Container) resolveKeyed(ctx context.Context, t reflect.Type, key interface{}) (interface{}, error) {
+	s, owner, ok := c.find(t, key)
+	if !ok {
+		return nil, ErrDependencyNotFound
 	}
 
-	return result.(T), nil
+	switch s.sType {
+	case singleton:
+		// singletons are always built and shared by the container owning them
+		if owner.isClosed() {
+			return nil, errContainerClosed
+		}
+		return owner.resolveSingleton(ctx, s)
+	case scoped:
+		return c.resolveScoped(ctx, t, key, s)
+	default:
+		return c.resolveTransient(ctx, s)
+	}
 }
 
-func resolve(ctx context.Context, t reflect.Type) (interface{}, error) {
-	if _, ok := singletonServices[t][0]; ok {
-		return resolveSingleton(t, 0)
-	} else if _, ok := transientServices[t][0]; ok {
-		return resolveTransient(ctx, t, 0)
-	} else if _, ok := scopedServices[t][0]; ok {
-		return resolveScoped(ctx, t, 0)
+// construct creates a new instance of the service and applies its decorators
+func (c *Container) construct(ctx context.Context, s service) (interface{}, error) {
+	var value interface{}
+	var err error
+
+	switch {
+	case s.instance:
+		value = s.value
+	case s.out != nil:
+		value, err = c.buildOutput(ctx, s)
+	default:
+		value, err = c.call(ctx, s.value)
 	}
 
-	return nil, errDependencyNotFound
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decorate(ctx, s, value)
 }
 
-func resolveKeyed(ctx context.Context, t reflect.Type, key interface{}) (interface{}, error) {
-	if _, ok := singletonServices[t][key]; ok {
-		return resolveSingleton(t, key)
-	} else if _, ok := transientServices[t][key]; ok {
-		return resolveTransient(ctx, t, key)
-	} else if _, ok := scopedServices[t][key]; ok {
-		return resolveScoped(ctx, t, key)
+// call invokes a provider resolving each of its parameters from the container.
+// Providers return the service, optionally followed by an error.
+func (c *Container) call(ctx context.Context, provider interface{}) (interface{}, error) {
+	return c.callWith(ctx, provider)
+}
+
+// callWith invokes a provider passing the leading arguments as given
+// and resolving the remaining ones from the container
+func (c *Container) callWith(ctx context.Context, provider interface{}, leading ...reflect.Value) (interface{}, error) {
+	tp := reflect.TypeOf(provider)
+	if tp.Kind() != reflect.Func {
+		return provider, nil
+	}
+
+	if err := validateProvider(tp); err != nil {
+		return nil, err
+	}
+
+	args := make([]reflect.Value, tp.NumIn())
+	copy(args, leading)
+	for i := len(leading); i < tp.NumIn(); i++ {
+		arg, err := c.resolveArgument(ctx, tp.In(i))
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
 	}
 
-	return nil, errDependencyNotFound
+	result := reflect.ValueOf(provider).Call(args)
+	if len(result) == 2 && !result[1].IsNil() {
+		return nil, result[1].Interface().(error)
+	}
+
+	return result[0].Interface(), nil
 }
 
-var mu sync.RWMutex
+func validateProvider(tp reflect.Type) error {
+	switch {
+	case tp.NumOut() == 1:
+		return nil
+	case tp.NumOut() == 2 && tp.Out(1) == errorType:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s must return the service, optionally followed by an error", errInvalidProvider, tp)
+}
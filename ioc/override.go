@@ -0,0 +1,55 @@
+package ioc
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Cleanup registers functions to run when a test finishes,
+// as implemented by *testing.T and *testing.B
+type Cleanup interface {
+	Cleanup(func())
+}
+
+// Override replaces the un-keyed registration of T in the default container
+// with the given instance until the test finishes, restoring the previous
+// registration afterwards
+//
+//	func TestOrders(t *testing.T) {
+//		ioc.Override[Repository](t, &fakeRepository{})
+//		...
+//	}
+func Override[T any](t Cleanup, instance T) {
+	OverrideKeyed[T](t, instance, 0)
+}
+
+func OverrideKeyed[T any](t Cleanup, instance T, key interface{}) {
+	t.Cleanup(defaultContainer.Override(TypeOf[T](), instance, key))
+}
+
+// Override registers instance as the singleton of t under key and returns
+// a function restoring the registration it replaced. The instance is not
+// disposed by the container.
+func (c *Container) Override(t reflect.Type, instance interface{}, key interface{}) (restore func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, existed := c.local(t, key)
+
+	s := newService(instance, singleton)
+	s.instance = true
+	s.seq = atomic.AddUint64(&sequence, 1)
+	c.store(t, key, s)
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if existed {
+			c.store(t, key, previous)
+			return
+		}
+
+		c.remove(t, key)
+	}
+}
@@ -0,0 +1,129 @@
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type mailer interface {
+	Send(to string) string
+}
+
+type smtpMailer struct{}
+
+func (smtpMailer) Send(to string) string { return "smtp:" + to }
+
+type fakeMailer struct{}
+
+func (fakeMailer) Send(to string) string { return "fake:" + to }
+
+type notifier interface {
+	Notify() string
+}
+
+type fakeNotifier struct{}
+
+func (fakeNotifier) Notify() string { return "fake" }
+
+func sendTo(t *testing.T, ctx context.Context) string {
+	t.Helper()
+	instance, err := ioc.Resolve[mailer](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instance.Send("alice")
+}
+
+func TestOverride(t *testing.T) {
+	// registered through Override too, so the default container is
+	// restored for the other tests once this one finishes
+	ioc.Override[mailer](t, smtpMailer{})
+	ctx := context.Background()
+
+	t.Run("override", func(t *testing.T) {
+		ioc.Override[mailer](t, fakeMailer{})
+
+		if got := sendTo(t, ctx); got != "fake:alice" {
+			t.Fatalf("expected the override, got %q", got)
+		}
+	})
+
+	if got := sendTo(t, ctx); got != "smtp:alice" {
+		t.Fatalf("expected the original registration to be restored, got %q", got)
+	}
+}
+
+func TestOverrideUnregistered(t *testing.T) {
+	t.Run("override", func(t *testing.T) {
+		ioc.Override[notifier](t, fakeNotifier{})
+
+		if _, err := ioc.Resolve[notifier](context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if _, err := ioc.Resolve[notifier](context.Background()); !errors.Is(err, ioc.ErrDependencyNotFound) {
+		t.Fatalf("expected the override to be removed, got %v", err)
+	}
+}
+
+func TestOverrideChild(t *testing.T) {
+	parent := ioc.New()
+	parent.RegisterSingleton(ioc.TypeOf[mailer](), smtpMailer{})
+
+	child := parent.NewChild()
+	restore := child.Override(ioc.TypeOf[mailer](), fakeMailer{}, 0)
+
+	parentCtx := ioc.WithContainer(context.Background(), parent)
+	childCtx := ioc.WithContainer(context.Background(), child)
+
+	if got := sendTo(t, childCtx); got != "fake:alice" {
+		t.Fatalf("expected the child to use the override, got %q", got)
+	}
+
+	if got := sendTo(t, parentCtx); got != "smtp:alice" {
+		t.Fatalf("expected the override not to leak into the parent, got %q", got)
+	}
+
+	restore()
+	if got := sendTo(t, childCtx); got != "smtp:alice" {
+		t.Fatalf("expected the child to inherit the parent registration again, got %q", got)
+	}
+}
+
+func TestOverrideRestoresDefaultContainer(t *testing.T) {
+	t.Run("override", func(t *testing.T) {
+		ioc.Override[mailer](t, smtpMailer{})
+
+		t.Run("nested", func(t *testing.T) {
+			ioc.Override[mailer](t, fakeMailer{})
+		})
+
+		if got := sendTo(t, context.Background()); got != "smtp:alice" {
+			t.Fatalf("expected the nested override to be restored, got %q", got)
+		}
+	})
+
+	if _, err := ioc.Resolve[mailer](context.Background()); !errors.Is(err, ioc.ErrDependencyNotFound) {
+		t.Fatalf("expected no registration left in the default container, got %v", err)
+	}
+}
+
+func TestOverrideContainer(t *testing.T) {
+	c := ioc.New()
+	c.RegisterSingleton(ioc.TypeOf[mailer](), smtpMailer{})
+	ctx := ioc.WithContainer(context.Background(), c)
+
+	restore := c.Override(ioc.TypeOf[mailer](), fakeMailer{}, 0)
+	if got := sendTo(t, ctx); got != "fake:alice" {
+		t.Fatalf("expected the override, got %q", got)
+	}
+
+	restore()
+	if got := sendTo(t, ctx); got != "smtp:alice" {
+		t.Fatalf("expected the original registration to be restored, got %q", got)
+	}
+}
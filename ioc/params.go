@@ -0,0 +1,225 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// In is embedded in a struct taken as provider parameter to resolve every
+// exported field of the struct independently. Fields tagged with
+// `ioc:"key=primary"` are resolved with the registration under that key,
+// and fields tagged with `ioc:"optional"` are left empty when their
+// service is not registered.
+//
+//	type RepositoryParams struct {
+//		ioc.In
+//		Primary  *sql.DB `ioc:"key=primary"`
+//		Replica  *sql.DB `ioc:"key=replica,optional"`
+//		Cache    Cache   `ioc:"optional"`
+//		Handlers []EventHandler
+//	}
+//
+//	func NewRepository(p RepositoryParams) *Repository
+type In struct{}
+
+var inType = reflect.TypeOf(In{})
+
+// argument is a service requested by a provider. Slices of services not
+// registered themselves are filled with every registration of their element.
+type argument struct {
+	Dependency
+	all      bool
+	optional bool
+}
+
+type fieldTag struct {
+	key      string
+	hasKey   bool
+	optional bool
+}
+
+func parseFieldTag(tag string) fieldTag {
+	result := fieldTag{}
+	for _, option := range strings.Split(tag, ",") {
+		option = strings.TrimSpace(option)
+		switch {
+		case strings.HasPrefix(option, "key="):
+			result.key = strings.TrimPrefix(option, "key=")
+			result.hasKey = true
+		case option == "optional":
+			result.optional = true
+		}
+	}
+	return result
+}
+
+func isParameterStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && embeds(t, inType)
+}
+
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedFields returns the fields of a parameter or result struct
+// handled by the container, skipping its marker
+func exportedFields(t reflect.Type, marker reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type == marker || field.PkgPath != "" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// arguments returns the services a provider parameter of type t depends on
+func (c *Container) arguments(t reflect.Type) []argument {
+	if !isParameterStruct(t) {
+		return []argument{c.argument(t, 0)}
+	}
+
+	fields := exportedFields(t, inType)
+	result := make([]argument, 0, len(fields))
+	for _, field := range fields {
+		result = append(result, c.fieldArgument(field))
+	}
+
+	return result
+}
+
+func (c *Container) argument(t reflect.Type, key interface{}) argument {
+	if t.Kind() == reflect.Slice && !c.isRegistered(t, key) {
+		return argument{Dependency: Dependency{Type: t.Elem(), Key: key}, all: true}
+	}
+
+	return argument{Dependency: Dependency{Type: t, Key: key}}
+}
+
+func (c *Container) fieldArgument(field reflect.StructField) argument {
+	tag := parseFieldTag(field.Tag.Get("ioc"))
+	if !tag.hasKey {
+		arg := c.argument(field.Type, 0)
+		arg.optional = tag.optional
+		return arg
+	}
+
+	key, ok := c.findKey(field.Type, tag.key)
+	if !ok {
+		key = tag.key
+	}
+
+	return argument{
+		Dependency: Dependency{Type: field.Type, Key: key},
+		optional:   tag.optional,
+	}
+}
+
+// resolveArgument resolves a provider parameter of type t
+func (c *Container) resolveArgument(ctx context.Context, t reflect.Type) (reflect.Value, error) {
+	if !isParameterStruct(t) {
+		return c.resolveValue(ctx, c.argument(t, 0), t)
+	}
+
+	result := reflect.New(t).Elem()
+	for _, field := range exportedFields(t, inType) {
+		value, err := c.resolveValue(ctx, c.fieldArgument(field), field.Type)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.FieldByIndex(field.Index).Set(value)
+	}
+
+	return result, nil
+}
+
+func (c *Container) resolveValue(ctx context.Context, arg argument, t reflect.Type) (reflect.Value, error) {
+	if arg.optional && !arg.all && !c.isRegistered(arg.Type, arg.Key) {
+		return reflect.Zero(t), nil
+	}
+
+	if !arg.all {
+		value, err := c.ResolveKeyed(ctx, arg.Type, arg.Key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return argumentValue(value, t)
+	}
+
+	values, err := c.ResolveAll(ctx, arg.Type)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	result := reflect.MakeSlice(t, 0, len(values))
+	for _, value := range values {
+		item, err := argumentValue(value, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result = reflect.Append(result, item)
+	}
+
+	return result, nil
+}
+
+func argumentValue(arg interface{}, t reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(t), nil
+	}
+
+	value := reflect.ValueOf(arg)
+	if !value.Type().AssignableTo(t) {
+		return reflect.Value{}, fmt.Errorf("%w: resolved %s is not assignable to %s", errInvalidProvider, value.Type(), t)
+	}
+
+	return value, nil
+}
+
+// ResolveAll returns an instance of every service registered for T, keyed
+// or not, in registration order
+func ResolveAll[T any](ctx context.Context) ([]T, error) {
+	values, err := FromContext(ctx).ResolveAll(ctx, TypeOf[T]())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]T, 0, len(values))
+	for _, value := range values {
+		item, ok := value.(T)
+		if !ok {
+			err := fmt.Errorf("%w: provider returned %T", errInvalidProvider, value)
+			return nil, resolutionError([]Dependency{{Type: TypeOf[T]()}}, err)
+		}
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+// ResolveAll returns an instance of every service registered for t, keyed
+// or not, in registration order
+func (c *Container) ResolveAll(ctx context.Context, t reflect.Type) ([]interface{}, error) {
+	keys := c.keys(t)
+
+	result := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value, err := c.ResolveKeyed(ctx, t, key)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,186 @@
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type eventHandler interface {
+	Handle() string
+}
+
+type namedHandler string
+
+func (h namedHandler) Handle() string { return string(h) }
+
+type eventBus struct {
+	handlers []eventHandler
+}
+
+func newEventBus(handlers []eventHandler) *eventBus {
+	return &eventBus{handlers: handlers}
+}
+
+func handled(handlers []eventHandler) []string {
+	result := make([]string, len(handlers))
+	for i, handler := range handlers {
+		result[i] = handler.Handle()
+	}
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func registerHandlers(c *ioc.Container) {
+	handler := ioc.TypeOf[eventHandler]()
+	c.RegisterKeyedSingleton(handler, eventHandler(namedHandler("audit")), "audit")
+	c.RegisterSingleton(handler, eventHandler(namedHandler("default")))
+	c.RegisterKeyedTransient(handler, func() eventHandler { return namedHandler("mail") }, 7)
+	c.RegisterKeyedScoped(handler, func() eventHandler { return namedHandler("cache") }, "cache")
+}
+
+func TestResolveAllRegistrationOrder(t *testing.T) {
+	c := ioc.New()
+	registerHandlers(c)
+
+	handlers, err := ioc.ResolveAll[eventHandler](ioc.WithContainer(context.Background(), c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"audit", "default", "mail", "cache"}
+	if got := handled(handlers); !equalStrings(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestResolveAllEmpty(t *testing.T) {
+	handlers, err := ioc.ResolveAll[eventHandler](ioc.WithContainer(context.Background(), ioc.New()))
+	if err != nil || len(handlers) != 0 {
+		t.Fatalf("expected no handlers, got %v, %v", handlers, err)
+	}
+}
+
+func TestSliceParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(c *ioc.Container)
+		expected []string
+	}{
+		{
+			name:     "every registration",
+			register: registerHandlers,
+			expected: []string{"audit", "default", "mail", "cache"},
+		},
+		{
+			name:     "no registration",
+			register: func(c *ioc.Container) {},
+			expected: []string{},
+		},
+		{
+			name: "registered slice",
+			register: func(c *ioc.Container) {
+				registerHandlers(c)
+				c.RegisterSingleton(ioc.TypeOf[[]eventHandler](), []eventHandler{namedHandler("explicit")})
+			},
+			expected: []string{"explicit"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := ioc.New()
+			test.register(c)
+			c.RegisterTransient(ioc.TypeOf[*eventBus](), newEventBus)
+
+			bus, err := ioc.Resolve[*eventBus](ioc.WithContainer(context.Background(), c))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := handled(bus.handlers); !equalStrings(got, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+type shard int
+
+type shardParams struct {
+	ioc.In
+	Primary  eventHandler `ioc:"key=primary"`
+	Numbered eventHandler `ioc:"key=2"`
+	Shard    eventHandler `ioc:"key=3"`
+	Missing  eventHandler `ioc:"key=missing,optional"`
+	Default  eventHandler `ioc:"optional"`
+	All      []eventHandler
+}
+
+type shardRouter struct {
+	params shardParams
+}
+
+func TestParameterStructKeys(t *testing.T) {
+	c := ioc.New()
+	handler := ioc.TypeOf[eventHandler]()
+	c.RegisterKeyedSingleton(handler, eventHandler(namedHandler("primary")), "primary")
+	c.RegisterKeyedSingleton(handler, eventHandler(namedHandler("two")), 2)
+	c.RegisterKeyedSingleton(handler, eventHandler(namedHandler("three")), shard(3))
+	c.RegisterTransient(ioc.TypeOf[*shardRouter](), func(p shardParams) *shardRouter {
+		return &shardRouter{params: p}
+	})
+
+	router, err := ioc.Resolve[*shardRouter](ioc.WithContainer(context.Background(), c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := router.params
+	if p.Primary.Handle() != "primary" || p.Numbered.Handle() != "two" || p.Shard.Handle() != "three" {
+		t.Fatalf("expected the keyed registrations, got %v, %v, %v", p.Primary, p.Numbered, p.Shard)
+	}
+
+	if p.Missing != nil || p.Default != nil {
+		t.Fatalf("expected the optional fields to be left empty, got %v, %v", p.Missing, p.Default)
+	}
+
+	if got := handled(p.All); !equalStrings(got, []string{"primary", "two", "three"}) {
+		t.Fatalf("expected every registration, got %v", got)
+	}
+}
+
+func TestParameterStructMissingKey(t *testing.T) {
+	type params struct {
+		ioc.In
+		Replica eventHandler `ioc:"key=replica"`
+	}
+
+	c := ioc.New()
+	c.RegisterTransient(ioc.TypeOf[*eventBus](), func(p params) *eventBus {
+		return &eventBus{handlers: []eventHandler{p.Replica}}
+	})
+
+	_, err := ioc.Resolve[*eventBus](ioc.WithContainer(context.Background(), c))
+	if !errors.Is(err, ioc.ErrDependencyNotFound) {
+		t.Fatalf("expected ErrDependencyNotFound, got %v", err)
+	}
+
+	var resolution *ioc.ResolutionError
+	if !errors.As(err, &resolution) || resolution.Path[len(resolution.Path)-1].String() != "ioc_test.eventHandler[key=replica]" {
+		t.Fatalf("expected the keyed dependency in the path, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Out is embedded in a struct returned by a provider to register every
+// exported field of the struct as a service. Fields tagged with
+// `ioc:"key=primary"` are registered under that key.
+//
+//	type Databases struct {
+//		ioc.Out
+//		Primary *sql.DB `ioc:"key=primary"`
+//		Replica *sql.DB `ioc:"key=replica"`
+//	}
+//
+//	func NewDatabases(config Config) (Databases, error)
+type Out struct{}
+
+var outType = reflect.TypeOf(Out{})
+
+// output links a service to the field of the Out struct it is read from.
+// Every field of the struct shares the same output, so the provider is
+// called once per singleton or scope for all of them.
+type output struct {
+	structType reflect.Type
+	index      []int
+	shared     *outputStruct
+}
+
+type outputStruct struct {
+	lazy *lazyInstance
+}
+
+// RegisterSingletonOut registers every field of the Out struct returned by
+// the provider as a singleton, built together on the first resolve of any of them
+func RegisterSingletonOut(provider interface{}, options ...SingletonOption) error {
+	return defaultContainer.RegisterSingletonOut(provider, options...)
+}
+
+// RegisterScopedOut registers every field of the Out struct returned by
+// the provider as a scoped service, built together once per scope
+func RegisterScopedOut(provider interface{}) error {
+	return defaultContainer.RegisterScopedOut(provider)
+}
+
+func (c *Container) RegisterSingletonOut(provider interface{}, options ...SingletonOption) error {
+	return c.registerOut(provider, singleton, options)
+}
+
+func (c *Container) RegisterScopedOut(provider interface{}) error {
+	return c.registerOut(provider, scoped, nil)
+}
+
+func (c *Container) registerOut(provider interface{}, lifetime serviceType, options []SingletonOption) error {
+	tp := reflect.TypeOf(provider)
+	if tp == nil || tp.Kind() != reflect.Func {
+		return fmt.Errorf("%w: %v is not a function", errInvalidProvider, tp)
+	}
+
+	if err := validateProvider(tp); err != nil {
+		return err
+	}
+
+	structType := tp.Out(0)
+	if !isResultStruct(structType) {
+		return fmt.Errorf("%w: %s does not embed ioc.Out", errInvalidProvider, structType)
+	}
+
+	shared := &outputStruct{lazy: &lazyInstance{}}
+	for _, field := range exportedFields(structType, outType) {
+		tag := parseFieldTag(field.Tag.Get("ioc"))
+
+		var key interface{} = 0
+		if tag.hasKey {
+			key = tag.key
+		}
+
+		s := newService(provider, lifetime)
+		s.out = &output{
+			structType: structType,
+			index:      field.Index,
+			shared:     shared,
+		}
+
+		if lifetime == singleton {
+			s.lazy = &lazyInstance{}
+			c.register(field.Type, key, s)
+			c.addEager(field.Type, key, options)
+			continue
+		}
+
+		c.register(field.Type, key, s)
+	}
+
+	return nil
+}
+
+func isResultStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && embeds(t, outType)
+}
+
+// buildOutput reads the service from the Out struct built by its provider,
+// calling the provider once per singleton or scope for all of the fields
+func (c *Container) buildOutput(ctx context.Context, s service) (interface{}, error) {
+	// the fields of the struct have different types, so a provider depending
+	// on a field of its own struct is detected through the struct itself,
+	// before waiting on the construction it would be part of
+	structDependency := Dependency{Type: s.out.structType, Key: 0}
+	if containsDependency(resolutionPath(ctx), structDependency) {
+		return nil, ErrCircularDependency
+	}
+	ctx, _ = withDependency(ctx, structDependency)
+
+	create := func() (interface{}, error) {
+		return c.call(ctx, s.value)
+	}
+
+	var result interface{}
+	var err error
+
+	switch scope := scopeFromContext(ctx); {
+	case s.sType == singleton:
+		result, err = s.out.shared.lazy.get(create)
+	case scope != nil && scope.container == c:
+		result, err = scope.resolve(s.out.structType, s.out.shared, create)
+	default:
+		result, err = create()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reflect.ValueOf(result).FieldByIndex(s.out.index).Interface(), nil
+}
@@ -0,0 +1,46 @@
+package ioc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type (
+	reader struct{}
+	writer struct{}
+)
+
+type storage struct {
+	ioc.Out
+	Reader *reader
+	Writer *writer
+}
+
+func TestOutProviderDependingOnSibling(t *testing.T) {
+	c := ioc.New()
+	err := c.RegisterSingletonOut(func(*writer) storage {
+		return storage{Reader: &reader{}, Writer: &writer{}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := ioc.Resolve[*reader](ioc.WithContainer(context.Background(), c))
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ioc.ErrCircularDependency) {
+			t.Fatalf("expected ErrCircularDependency, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolution deadlocked")
+	}
+}
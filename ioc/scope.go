@@ -0,0 +1,107 @@
+package ioc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Scope caches the scoped services resolved with its context, so every
+// resolve within the scope returns the same instance
+type Scope struct {
+	container *Container
+	mu        sync.Mutex
+	instances map[serviceKey]*scopedInstance
+	created   []interface{}
+	closed    bool
+}
+
+type serviceKey struct {
+	t   reflect.Type
+	key interface{}
+}
+
+type scopedInstance struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+type scopeContextKey struct{}
+
+// NewScope opens a scope for the container bound to ctx and returns
+// a context carrying it. The scope must be closed once it is no longer used.
+func NewScope(ctx context.Context) (context.Context, *Scope) {
+	scope := &Scope{
+		container: FromContext(ctx),
+		instances: make(map[serviceKey]*scopedInstance),
+	}
+
+	return context.WithValue(ctx, scopeContextKey{}, scope), scope
+}
+
+func scopeFromContext(ctx context.Context) *Scope {
+	if ctx == nil {
+		return nil
+	}
+
+	scope, _ := ctx.Value(scopeContextKey{}).(*Scope)
+	return scope
+}
+
+// Close ends the scope, disposing the instances created within it
+// in reverse creation order
+func (s *Scope) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	instances := s.created
+	s.closed = true
+	s.instances = make(map[serviceKey]*scopedInstance)
+	s.created = nil
+	s.mu.Unlock()
+
+	return disposeAll(instances)
+}
+
+// resolve returns the cached instance for t and key, creating it once
+// even when resolved concurrently
+func (s *Scope) resolve(t reflect.Type, key interface{}, create func() (interface{}, error)) (interface{}, error) {
+	k := serviceKey{t: t, key: key}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errScopeClosed
+	}
+
+	instance, ok := s.instances[k]
+	if !ok {
+		instance = &scopedInstance{}
+		s.instances[k] = instance
+	}
+	s.mu.Unlock()
+
+	instance.once.Do(func() {
+		instance.value, instance.err = create()
+		if instance.err == nil && isDisposable(instance.value) {
+			s.mu.Lock()
+			s.created = append(s.created, instance.value)
+			s.mu.Unlock()
+		}
+	})
+
+	// failed constructions are not cached so they can be retried
+	if instance.err != nil {
+		s.mu.Lock()
+		if s.instances[k] == instance {
+			delete(s.instances, k)
+		}
+		s.mu.Unlock()
+	}
+
+	return instance.value, instance.err
+}
@@ -6,60 +6,31 @@ import (
 )
 
 func RegisterScoped[T any](provider interface{}) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
-
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, ok := scopedServices[tp]; !ok {
-		scopedServices[tp] = make(map[interface{}]service)
-	}
-	scopedServices[tp][0] = newService(provider, scoped)
+	defaultContainer.RegisterScoped(TypeOf[T](), provider)
 }
 
 func RegisterKeyedScoped[T any](provider interface{}, key interface{}) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
-
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, ok := scopedServices[tp]; !ok {
-		scopedServices[tp] = make(map[interface{}]service)
-	}
-	scopedServices[tp][key] = newService(provider, scoped)
+	defaultContainer.RegisterKeyedScoped(TypeOf[T](), provider, key)
 }
 
-func resolveScoped(ctx context.Context, t reflect.Type, key interface{}) (interface{}, error) {
-	mu.RLock()
-	defer mu.RUnlock()
-
-	service := ctx.Value(t)
-	if service != nil {
-		return service, nil
-	}
-
-	provider, ok := scopedServices[t][key]
-	if !ok {
-		return nil, errDependencyNotFound
-	}
+// RegisterScoped registers a provider creating one instance of t per scope
+func (c *Container) RegisterScoped(t reflect.Type, provider interface{}) {
+	c.RegisterKeyedScoped(t, provider, 0)
+}
 
-	tp := reflect.TypeOf(provider.value)
-	if tp.Kind() != reflect.Func {
-		return provider.value, nil
-	}
+func (c *Container) RegisterKeyedScoped(t reflect.Type, provider interface{}, key interface{}) {
+	c.register(t, key, newService(provider, scoped))
+}
 
-	args := make([]reflect.Value, tp.NumIn())
-	for i := 0; i < tp.NumIn(); i++ {
-		argType := tp.In(i)
-		arg, err := resolve(ctx, argType)
-		if err != nil {
-			return nil, err
-		}
-		args[i] = reflect.ValueOf(arg)
+// resolveScoped returns the instance cached in the scope of ctx. Without
+// a scope a new instance is created on every resolve.
+func (c *Container) resolveScoped(ctx context.Context, t reflect.Type, key interface{}, provider service) (interface{}, error) {
+	scope := scopeFromContext(ctx)
+	if scope == nil || scope.container != c {
+		return c.construct(ctx, provider)
 	}
 
-	result := reflect.ValueOf(provider.value).Call(args)
-	ctx = context.WithValue(ctx, t, result[0].Interface())
-
-	return result[0].Interface(), nil
+	return scope.resolve(t, key, func() (interface{}, error) {
+		return c.construct(ctx, provider)
+	})
 }
@@ -1,47 +1,162 @@
 package ioc
 
 import (
+	"context"
 	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 func RegisterSingleton[T any](instance T) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
+	defaultContainer.RegisterSingleton(TypeOf[T](), instance)
+}
+
+func RegisterKeyedSingleton[T any](instance T, key interface{}) {
+	defaultContainer.RegisterKeyedSingleton(TypeOf[T](), instance, key)
+}
+
+// RegisterSingletonFactory registers a provider building the singleton on its
+// first resolve, so it can depend on other registered services
+func RegisterSingletonFactory[T any](provider interface{}, options ...SingletonOption) {
+	defaultContainer.RegisterSingletonFactory(TypeOf[T](), provider, options...)
+}
+
+func RegisterKeyedSingletonFactory[T any](provider interface{}, key interface{}, options ...SingletonOption) {
+	defaultContainer.RegisterKeyedSingletonFactory(TypeOf[T](), provider, key, options...)
+}
 
-	mu.Lock()
-	defer mu.Unlock()
+// SingletonOption configures a singleton factory registration
+type SingletonOption func(*singletonOptions)
+
+type singletonOptions struct {
+	eager bool
+}
 
-	if _, ok := singletonServices[tp]; !ok {
-		singletonServices[tp] = make(map[interface{}]service)
+// Eager builds the singleton when the container is initialized
+// instead of on its first resolve
+func Eager() SingletonOption {
+	return func(o *singletonOptions) {
+		o.eager = true
 	}
+}
 
-	singletonServices[tp][0] = newService(instance, singleton)
+// lazyInstance builds a singleton exactly once. Failed constructions
+// are not cached so they are retried on the next resolve.
+type lazyInstance struct {
+	mu    sync.Mutex
+	done  bool
+	value interface{}
 }
 
-func RegisterKeyedSingleton[T any](instance T, key interface{}) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
+// built reports whether the singleton has been constructed
+func (l *lazyInstance) built() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done
+}
+
+func (l *lazyInstance) get(create func() (interface{}, error)) (interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done {
+		return l.value, nil
+	}
+
+	value, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	l.value = value
+	l.done = true
+	return value, nil
+}
+
+// RegisterSingleton registers an instance of t shared by every resolve
+func (c *Container) RegisterSingleton(t reflect.Type, instance interface{}) {
+	c.RegisterKeyedSingleton(t, instance, 0)
+}
+
+func (c *Container) RegisterKeyedSingleton(t reflect.Type, instance interface{}, key interface{}) {
+	s := newService(instance, singleton)
+	s.instance = true
+	s.resolved = new(atomic.Bool)
+	c.register(t, key, s)
+	c.track(instance)
+}
 
-	mu.Lock()
-	defer mu.Unlock()
+func (c *Container) RegisterSingletonFactory(t reflect.Type, provider interface{}, options ...SingletonOption) {
+	c.RegisterKeyedSingletonFactory(t, provider, 0, options...)
+}
+
+func (c *Container) RegisterKeyedSingletonFactory(t reflect.Type, provider interface{}, key interface{}, options ...SingletonOption) {
+	s := newService(provider, singleton)
+	s.lazy = &lazyInstance{}
+	c.register(t, key, s)
+	c.addEager(t, key, options)
+}
+
+func (c *Container) addEager(t reflect.Type, key interface{}, options []SingletonOption) {
+	config := singletonOptions{}
+	for _, option := range options {
+		option(&config)
+	}
 
-	if _, ok := singletonServices[tp]; !ok {
-		singletonServices[tp] = make(map[interface{}]service)
+	if !config.eager {
+		return
 	}
 
-	singletonServices[tp][key] = newService(instance, singleton)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.eager = append(c.eager, serviceKey{t: t, key: key})
 }
 
-func resolveSingleton(t reflect.Type, key interface{}) (interface{}, error) {
-	mu.RLock()
-	defer mu.RUnlock()
+// Initialize builds the singletons registered with the Eager option,
+// in registration order
+func (c *Container) Initialize(ctx context.Context) error {
+	c.mu.RLock()
+	eager := append([]serviceKey(nil), c.eager...)
+	c.mu.RUnlock()
+
+	for _, k := range eager {
+		if _, err := c.ResolveKeyed(ctx, k.t, k.key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Initialize builds the eager singletons of the default container
+func Initialize(ctx context.Context) error {
+	return defaultContainer.Initialize(ctx)
+}
+
+func (c *Container) resolveSingleton(ctx context.Context, instance service) (interface{}, error) {
+	if instance.lazy != nil {
+		// singletons outlive any scope, so their dependencies
+		// are never resolved from the caller scope
+		root := context.WithValue(ctx, scopeContextKey{}, (*Scope)(nil))
+
+		return instance.lazy.get(func() (interface{}, error) {
+			value, err := c.construct(root, instance)
+			if err != nil {
+				return nil, err
+			}
 
-	instance, ok := singletonServices[t][key]
-	if !ok {
-		return nil, errDependencyNotFound
+			c.track(value)
+			return value, nil
+		})
 	}
 
 	if instance.value != nil {
+		if instance.resolved != nil {
+			instance.resolved.Store(true)
+		}
 		return instance.value, nil
 	}
 
-	return nil, errDependencyNotFound
+	return nil, ErrDependencyNotFound
 }
@@ -0,0 +1,57 @@
+package ioc_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type clock struct {
+	id int32
+}
+
+func TestSingletonFactoryBuiltOnce(t *testing.T) {
+	var built int32
+	c := ioc.New()
+	c.RegisterSingletonFactory(ioc.TypeOf[*clock](), func() *clock {
+		// a slow constructor widens the window for double construction
+		time.Sleep(10 * time.Millisecond)
+		return &clock{id: atomic.AddInt32(&built, 1)}
+	})
+
+	ctx := ioc.WithContainer(context.Background(), c)
+	results := make([]*clock, 64)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			instance, err := ioc.Resolve[*clock](ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = instance
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if built != 1 {
+		t.Fatalf("expected the constructor to run once, ran %d times", built)
+	}
+
+	for _, instance := range results {
+		if instance != results[0] {
+			t.Fatal("expected every goroutine to receive the same instance")
+		}
+	}
+}
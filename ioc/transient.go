@@ -6,53 +6,22 @@ import (
 )
 
 func RegisterTransient[T any](provider interface{}) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
-
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, ok := transientServices[tp]; !ok {
-		transientServices[tp] = make(map[interface{}]service)
-	}
-	transientServices[tp][0] = newService(provider, transient)
+	defaultContainer.RegisterTransient(TypeOf[T](), provider)
 }
 
 func RegisterKeyedTransient[T any](provider interface{}, key interface{}) {
-	tp := reflect.TypeOf((*T)(nil)).Elem()
-
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, ok := transientServices[tp]; !ok {
-		transientServices[tp] = make(map[interface{}]service)
-	}
-	transientServices[tp][key] = newService(provider, transient)
+	defaultContainer.RegisterKeyedTransient(TypeOf[T](), provider, key)
 }
 
-func resolveTransient(ctx context.Context, t reflect.Type, key interface{}) (interface{}, error) {
-	mu.RLock()
-	defer mu.RUnlock()
-
-	provider, ok := transientServices[t][key]
-	if !ok {
-		return nil, errDependencyNotFound
-	}
-
-	tp := reflect.TypeOf(provider.value)
-	if tp.Kind() != reflect.Func {
-		return provider.value, nil
-	}
+// RegisterTransient registers a provider creating a new instance of t on every resolve
+func (c *Container) RegisterTransient(t reflect.Type, provider interface{}) {
+	c.RegisterKeyedTransient(t, provider, 0)
+}
 
-	args := make([]reflect.Value, tp.NumIn())
-	for i := 0; i < tp.NumIn(); i++ {
-		argType := tp.In(i)
-		arg, err := resolve(ctx, argType)
-		if err != nil {
-			return nil, err
-		}
-		args[i] = reflect.ValueOf(arg)
-	}
+func (c *Container) RegisterKeyedTransient(t reflect.Type, provider interface{}, key interface{}) {
+	c.register(t, key, newService(provider, transient))
+}
 
-	result := reflect.ValueOf(provider.value).Call(args)
-	return result[0].Interface(), nil
+func (c *Container) resolveTransient(ctx context.Context, provider service) (interface{}, error) {
+	return c.construct(ctx, provider)
 }
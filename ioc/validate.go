@@ -0,0 +1,219 @@
+package ioc
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+)
+
+var (
+	// ErrCircularDependency is returned when a provider depends, directly
+	// or through other providers, on the service it provides
+	ErrCircularDependency = errors.New("circular dependency")
+
+	// ErrCaptiveDependency is reported by Validate when a singleton depends
+	// on a scoped service, which would outlive the scope it belongs to
+	ErrCaptiveDependency = errors.New("captive dependency: singleton depends on scoped service")
+)
+
+// registration is a snapshot of a registered service used to inspect the container
+type registration struct {
+	dependency Dependency
+	lifetime   serviceType
+	provider   interface{}
+	factory    bool
+	seq        uint64
+	decorators []interface{}
+}
+
+func (c *Container) registrations() []registration {
+	result := make([]registration, 0)
+	if c.parent != nil {
+		result = c.parent.registrations()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// inherited registrations overridden by c are left out
+	inherited := result[:0]
+	for _, r := range result {
+		if _, ok := c.local(r.dependency.Type, r.dependency.Key); !ok {
+			inherited = append(inherited, r)
+		}
+	}
+	result = inherited
+
+	for _, services := range c.lifetimes() {
+		for t, keyed := range services {
+			for key, s := range keyed {
+				result = append(result, registration{
+					dependency: Dependency{Type: t, Key: key},
+					lifetime:   s.sType,
+					provider:   s.value,
+					factory:    !s.instance,
+					seq:        s.seq,
+					decorators: s.decorators,
+				})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].seq < result[j].seq
+	})
+
+	return result
+}
+
+// providerArguments returns the services requested by the provider
+// and the decorators of the registration
+func (c *Container) providerArguments(r registration) []argument {
+	result := make([]argument, 0)
+
+	if tp := reflect.TypeOf(r.provider); r.factory && tp != nil && tp.Kind() == reflect.Func {
+		for i := 0; i < tp.NumIn(); i++ {
+			result = append(result, c.arguments(tp.In(i))...)
+		}
+	}
+
+	// the first parameter of a decorator receives the decorated service
+	for _, decorator := range r.decorators {
+		tp := reflect.TypeOf(decorator)
+		for i := 1; i < tp.NumIn(); i++ {
+			result = append(result, c.arguments(tp.In(i))...)
+		}
+	}
+
+	return result
+}
+
+// dependencies returns the registrations the provider of r depends on,
+// expanding slices into every registration of their element
+func (c *Container) dependencies(r registration) []Dependency {
+	result := make([]Dependency, 0)
+	for _, arg := range c.providerArguments(r) {
+		if !arg.all {
+			result = append(result, arg.Dependency)
+			continue
+		}
+
+		for _, key := range c.keys(arg.Type) {
+			result = append(result, Dependency{Type: arg.Type, Key: key})
+		}
+	}
+
+	return result
+}
+
+// Validate walks the providers of every registered service and reports
+// missing registrations, circular dependencies, invalid providers and
+// singletons depending on scoped services. Every problem is returned as
+// a *ResolutionError joined in a single error.
+func (c *Container) Validate() error {
+	registrations := c.registrations()
+
+	index := make(map[Dependency]registration, len(registrations))
+	for _, r := range registrations {
+		index[r.dependency] = r
+	}
+
+	errs := make([]error, 0)
+	report := func(path []Dependency, err error) {
+		errs = append(errs, &ResolutionError{
+			Path: append([]Dependency(nil), path...),
+			Err:  err,
+		})
+	}
+
+	for _, r := range registrations {
+		if tp := reflect.TypeOf(r.provider); r.factory && tp != nil && tp.Kind() == reflect.Func {
+			if err := validateProvider(tp); err != nil {
+				report([]Dependency{r.dependency}, err)
+			}
+		}
+
+		for _, arg := range c.providerArguments(r) {
+			if _, ok := index[arg.Dependency]; !ok && !arg.all && !arg.optional {
+				report([]Dependency{r.dependency, arg.Dependency}, ErrDependencyNotFound)
+			}
+		}
+	}
+
+	c.validateCycles(registrations, index, report)
+
+	for _, r := range registrations {
+		if r.lifetime == singleton && r.factory {
+			c.validateCaptive(index, []Dependency{r.dependency}, make(map[Dependency]bool), report)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateCycles reports every cycle of the dependency graph once,
+// using a depth first search that tracks the dependencies on the stack
+func (c *Container) validateCycles(registrations []registration, index map[Dependency]registration, report func([]Dependency, error)) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[Dependency]int)
+
+	var visit func(path []Dependency)
+	visit = func(path []Dependency) {
+		current := path[len(path)-1]
+		state[current] = visiting
+
+		for _, dependency := range c.dependencies(index[current]) {
+			if _, ok := index[dependency]; !ok {
+				continue
+			}
+
+			switch state[dependency] {
+			case visiting:
+				start := 0
+				for path[start] != dependency {
+					start++
+				}
+				report(append(path[start:len(path):len(path)], dependency), ErrCircularDependency)
+			case 0:
+				visit(append(path[:len(path):len(path)], dependency))
+			}
+		}
+
+		state[current] = visited
+	}
+
+	for _, r := range registrations {
+		if state[r.dependency] == 0 {
+			visit([]Dependency{r.dependency})
+		}
+	}
+}
+
+// validateCaptive reports the scoped services a singleton reaches
+// directly or through transient services
+func (c *Container) validateCaptive(index map[Dependency]registration, path []Dependency, seen map[Dependency]bool, report func([]Dependency, error)) {
+	for _, dependency := range c.dependencies(index[path[len(path)-1]]) {
+		next, ok := index[dependency]
+		if !ok || seen[dependency] {
+			continue
+		}
+		seen[dependency] = true
+
+		current := append(path[:len(path):len(path)], dependency)
+		switch next.lifetime {
+		case scoped:
+			report(current, ErrCaptiveDependency)
+		case transient:
+			c.validateCaptive(index, current, seen, report)
+		}
+	}
+}
+
+// Validate validates the default container
+func Validate() error {
+	return defaultContainer.Validate()
+}
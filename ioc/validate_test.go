@@ -0,0 +1,96 @@
+package ioc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ramoncl001/go-comet/ioc"
+)
+
+type (
+	serviceA struct{ b *serviceB }
+	serviceB struct{ a *serviceA }
+	config   struct{}
+	session  struct{}
+	cache    struct{ session *session }
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(c *ioc.Container)
+		err      error
+		path     []ioc.Dependency
+	}{
+		{
+			name: "valid",
+			register: func(c *ioc.Container) {
+				c.RegisterSingleton(ioc.TypeOf[*config](), &config{})
+				c.RegisterScoped(ioc.TypeOf[*session](), func(*config) *session { return &session{} })
+			},
+		},
+		{
+			name: "missing dependency",
+			register: func(c *ioc.Container) {
+				c.RegisterScoped(ioc.TypeOf[*session](), func(*config) *session { return &session{} })
+			},
+			err:  ioc.ErrDependencyNotFound,
+			path: []ioc.Dependency{{Type: ioc.TypeOf[*session](), Key: 0}, {Type: ioc.TypeOf[*config](), Key: 0}},
+		},
+		{
+			name: "circular dependency",
+			register: func(c *ioc.Container) {
+				c.RegisterTransient(ioc.TypeOf[*serviceA](), func(b *serviceB) *serviceA { return &serviceA{b: b} })
+				c.RegisterTransient(ioc.TypeOf[*serviceB](), func(a *serviceA) *serviceB { return &serviceB{a: a} })
+			},
+			err: ioc.ErrCircularDependency,
+			path: []ioc.Dependency{
+				{Type: ioc.TypeOf[*serviceA](), Key: 0},
+				{Type: ioc.TypeOf[*serviceB](), Key: 0},
+				{Type: ioc.TypeOf[*serviceA](), Key: 0},
+			},
+		},
+		{
+			name: "singleton depending on scoped service",
+			register: func(c *ioc.Container) {
+				c.RegisterScoped(ioc.TypeOf[*session](), func() *session { return &session{} })
+				c.RegisterSingletonFactory(ioc.TypeOf[*cache](), func(s *session) *cache { return &cache{session: s} })
+			},
+			err:  ioc.ErrCaptiveDependency,
+			path: []ioc.Dependency{{Type: ioc.TypeOf[*cache](), Key: 0}, {Type: ioc.TypeOf[*session](), Key: 0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := ioc.New()
+			test.register(c)
+
+			err := c.Validate()
+			if test.err == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+
+			var resolution *ioc.ResolutionError
+			if !errors.As(err, &resolution) {
+				t.Fatalf("expected a *ioc.ResolutionError, got %T", err)
+			}
+
+			if len(resolution.Path) != len(test.path) {
+				t.Fatalf("expected path %v, got %v", test.path, resolution.Path)
+			}
+			for i := range test.path {
+				if resolution.Path[i] != test.path[i] {
+					t.Fatalf("expected path %v, got %v", test.path, resolution.Path)
+				}
+			}
+		})
+	}
+}
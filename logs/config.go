@@ -0,0 +1,215 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Format string
+
+const (
+	TextFormat Format = "text"
+	JSONFormat Format = "json"
+)
+
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+)
+
+// Config configures the handler shared by every logger
+type Config struct {
+	// Format of the records, TextFormat by default
+	Format Format
+	// Level is the minimum level of the records written
+	Level slog.Level
+	// Output receives the records, os.Stdout by default
+	Output io.Writer
+	// File writes the records to a rotated file instead of Output
+	File *FileConfig
+	// AddSource includes the file and line of the log call
+	AddSource bool
+	// Redaction removes sensitive values from every record
+	Redaction Redaction
+}
+
+var (
+	mu        sync.RWMutex
+	once      sync.Once
+	level     = new(slog.LevelVar)
+	handler   slog.Handler
+	redaction *redactor
+	sink      *FileSink
+	// generation is incremented whenever the handler is replaced, so the
+	// handlers derived from the previous one are derived again
+	generation uint64
+)
+
+// Configure replaces the handler shared by every logger. Loggers
+// already returned by FromContext use the new configuration.
+func Configure(config Config) error {
+	// the environment is not read once configured explicitly
+	once.Do(func() {})
+	return configure(config)
+}
+
+func configure(config Config) error {
+	output := config.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	var file *FileSink
+	if config.File != nil {
+		var err error
+		if file, err = NewFileSink(*config.File); err != nil {
+			return err
+		}
+		output = file
+	}
+
+	level.Set(config.Level)
+	options := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: config.AddSource,
+	}
+
+	var h slog.Handler
+	if config.Format == JSONFormat {
+		h = slog.NewJSONHandler(output, options)
+	} else {
+		h = slog.NewTextHandler(output, options)
+	}
+
+	// the handler is wrapped even without rules, so the struct fields
+	// tagged `log:"redact"` are never written
+	r := newRedactor(config.Redaction)
+	h = &redactingHandler{next: h, redactor: r}
+	if config.Redaction.empty() {
+		r = nil
+	}
+
+	mu.Lock()
+	previous := sink
+	handler = h
+	redaction = r
+	sink = file
+	generation++
+	mu.Unlock()
+
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// Close closes the file of the configuration, if any, waiting
+// for the rotated files being compressed
+func Close() error {
+	mu.RLock()
+	file := sink
+	mu.RUnlock()
+
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
+
+// SetLevel changes the minimum level of the records written at runtime
+func SetLevel(l slog.Level) {
+	current()
+	level.Set(l)
+}
+
+// GetLevel returns the minimum level of the records written
+func GetLevel() slog.Level {
+	current()
+	return level.Level()
+}
+
+// ParseLevel parses a level name like "debug" or "warn", optionally
+// followed by an offset like "info+2"
+func ParseLevel(value string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, fmt.Errorf("logs: invalid level %q", value)
+	}
+	return l, nil
+}
+
+// ConfigFromEnv reads the configuration from the COMET_LOG_LEVEL,
+// COMET_LOG_FORMAT, COMET_LOG_SOURCE and COMET_LOG_FILE environment
+// variables, logging debug records as text to stdout when they are not set
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Format: TextFormat,
+		Level:  LevelDebug,
+	}
+
+	if value := os.Getenv("COMET_LOG_LEVEL"); value != "" {
+		l, err := ParseLevel(value)
+		if err != nil {
+			return config, err
+		}
+		config.Level = l
+	}
+
+	switch format := Format(strings.ToLower(os.Getenv("COMET_LOG_FORMAT"))); format {
+	case "":
+	case TextFormat, JSONFormat:
+		config.Format = format
+	default:
+		return config, fmt.Errorf("logs: invalid format %q", format)
+	}
+
+	if value := os.Getenv("COMET_LOG_SOURCE"); value != "" {
+		source, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("logs: invalid COMET_LOG_SOURCE %q", value)
+		}
+		config.AddSource = source
+	}
+
+	if path := os.Getenv("COMET_LOG_FILE"); path != "" {
+		config.File = &FileConfig{Path: path}
+	}
+
+	return config, nil
+}
+
+// current returns the shared handler, configured from the environment
+// on first use unless Configure was called before
+func current() slog.Handler {
+	once.Do(func() {
+		config, err := ConfigFromEnv()
+		if err == nil {
+			err = configure(config)
+		}
+
+		if err != nil {
+			configure(Config{Level: LevelDebug})
+			slog.New(handler).Warn("invalid logging configuration", "error", err)
+		}
+	})
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return handler
+}
+
+// shared returns the shared handler and its generation
+func shared() (slog.Handler, uint64) {
+	current()
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return handler, generation
+}
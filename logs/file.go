@@ -0,0 +1,299 @@
+package logs
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeFormat = "20060102T150405.000"
+
+// FileConfig configures a FileSink
+type FileConfig struct {
+	// Path of the file records are written to
+	Path string
+	// MaxSize in bytes the file reaches before being rotated, no limit when zero
+	MaxSize int64
+	// RotateEvery rotates the file periodically, like every 24 hours
+	RotateEvery time.Duration
+	// MaxFiles retained after rotation, every file when zero
+	MaxFiles int
+	// MaxAge of the retained files, no limit when zero
+	MaxAge time.Duration
+	// Compress rotated files with gzip
+	Compress bool
+}
+
+// FileSink is a writer appending to a file that is rotated by size and time.
+// Rotated files are renamed with the rotation time, like app-20240102T150405.000.log,
+// optionally compressed, and removed once they exceed MaxFiles or MaxAge.
+// It is safe for concurrent use.
+type FileSink struct {
+	config FileConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	closed bool
+	size   int64
+	opened time.Time
+	// pending tracks the compression and cleanup running after rotations,
+	// which maintenance runs one at a time
+	pending     sync.WaitGroup
+	maintenance sync.Mutex
+}
+
+func NewFileSink(config FileConfig) (*FileSink, error) {
+	if config.Path == "" {
+		return nil, errors.New("logs: file sink requires a path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &FileSink{config: config}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reopen(); err != nil {
+		return 0, err
+	}
+
+	if s.shouldRotate(int64(len(p))) {
+		if err := s.rotate(); err != nil {
+			if s.file == nil {
+				return 0, err
+			}
+			// the records keep being written to the file not rotated
+			fmt.Fprintf(os.Stderr, "logs: unable to rotate %s: %v\n", s.config.Path, err)
+		}
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file and starts a new one
+func (s *FileSink) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reopen(); err != nil {
+		return err
+	}
+
+	return s.rotate()
+}
+
+// reopen opens the file again when a failed rotation left none open,
+// so writing resumes once the cause is fixed. The lock must be held.
+func (s *FileSink) reopen() error {
+	if s.closed {
+		return os.ErrClosed
+	}
+
+	if s.file != nil {
+		return nil
+	}
+
+	return s.open()
+}
+
+// Close closes the file, waiting for the rotated files being compressed
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	var err error
+	if s.file != nil {
+		err = s.file.Close()
+		s.file = nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.pending.Wait()
+	return err
+}
+
+func (s *FileSink) shouldRotate(size int64) bool {
+	if s.config.MaxSize > 0 && s.size > 0 && s.size+size > s.config.MaxSize {
+		return true
+	}
+
+	return s.config.RotateEvery > 0 && time.Since(s.opened) >= s.config.RotateEvery
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	s.opened = time.Now()
+	return nil
+}
+
+// rotate renames the current file and opens a new one, the lock must be
+// held. On failure the file is left nil when it can't be reopened.
+func (s *FileSink) rotate() error {
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return err
+	}
+
+	rotated := s.rotatedName(time.Now())
+	if err := os.Rename(s.config.Path, rotated); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// the original file is reopened so writing goes on
+		if openErr := s.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+
+		s.maintenance.Lock()
+		defer s.maintenance.Unlock()
+
+		// the file may have been removed by the cleanup of a previous rotation
+		if s.config.Compress {
+			if err := compressFile(rotated); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "logs: unable to compress %s: %v\n", rotated, err)
+			}
+		}
+
+		s.cleanup()
+	}()
+
+	return nil
+}
+
+// rotatedName returns an unused name for the file rotated at t
+func (s *FileSink) rotatedName(t time.Time) string {
+	ext := filepath.Ext(s.config.Path)
+	base := strings.TrimSuffix(s.config.Path, ext)
+
+	for {
+		name := fmt.Sprintf("%s-%s%s", base, t.Format(rotatedTimeFormat), ext)
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// cleanup removes the rotated files exceeding MaxFiles or MaxAge
+func (s *FileSink) cleanup() {
+	if s.config.MaxFiles <= 0 && s.config.MaxAge <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(s.config.Path)
+	prefix := filepath.Base(strings.TrimSuffix(s.config.Path, ext)) + "-"
+	dir := filepath.Dir(s.config.Path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	rotated := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err == nil {
+			rotated = append(rotated, name)
+		}
+	}
+
+	// the time format sorts by name, newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+
+	for i, name := range rotated {
+		path := filepath.Join(dir, name)
+
+		expired := false
+		if s.config.MaxAge > 0 {
+			if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > s.config.MaxAge {
+				expired = true
+			}
+		}
+
+		if expired || s.config.MaxFiles > 0 && i >= s.config.MaxFiles {
+			os.Remove(path)
+		}
+	}
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(target)
+	if _, err := io.Copy(writer, source); err != nil {
+		target.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		target.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := target.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	source.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,255 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rotatedFiles returns the rotated files of the sink at path
+func rotatedFiles(t *testing.T, path string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ".log") + "-[0-9]*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func newTestSink(t *testing.T, config FileConfig) *FileSink {
+	t.Helper()
+
+	if config.Path == "" {
+		config.Path = filepath.Join(t.TempDir(), "app.log")
+	}
+
+	sink, err := NewFileSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		sink.Close()
+	})
+	return sink
+}
+
+func TestFileSinkRotatesBySize(t *testing.T) {
+	sink := newTestSink(t, FileConfig{MaxSize: 100})
+
+	record := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := sink.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+
+	rotated := rotatedFiles(t, sink.config.Path)
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", rotated)
+	}
+
+	for _, path := range append(rotated, sink.config.Path) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != record {
+			t.Fatalf("expected a single record in %s, got %q", path, content)
+		}
+	}
+}
+
+func TestFileSinkRotatesByTime(t *testing.T) {
+	sink := newTestSink(t, FileConfig{RotateEvery: 20 * time.Millisecond})
+
+	sink.Write([]byte("first\n"))
+	sink.Write([]byte("second\n"))
+	time.Sleep(30 * time.Millisecond)
+	sink.Write([]byte("third\n"))
+	sink.Close()
+
+	rotated := rotatedFiles(t, sink.config.Path)
+	if len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated file, got %v", rotated)
+	}
+
+	if content, _ := os.ReadFile(rotated[0]); string(content) != "first\nsecond\n" {
+		t.Fatalf("unexpected rotated content %q", content)
+	}
+
+	if content, _ := os.ReadFile(sink.config.Path); string(content) != "third\n" {
+		t.Fatalf("unexpected current content %q", content)
+	}
+}
+
+func TestFileSinkCompress(t *testing.T) {
+	sink := newTestSink(t, FileConfig{Compress: true})
+
+	sink.Write([]byte("compressed\n"))
+	if err := sink.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	rotated := rotatedFiles(t, sink.config.Path)
+	if len(rotated) != 1 || !strings.HasSuffix(rotated[0], ".log.gz") {
+		t.Fatalf("expected only the compressed file, got %v", rotated)
+	}
+
+	file, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content, _ := io.ReadAll(reader); string(content) != "compressed\n" {
+		t.Fatalf("unexpected compressed content %q", content)
+	}
+}
+
+func TestFileSinkMaxFiles(t *testing.T) {
+	sink := newTestSink(t, FileConfig{MaxFiles: 2})
+
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(sink, "record %d\n", i)
+		if err := sink.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+
+	rotated := rotatedFiles(t, sink.config.Path)
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", rotated)
+	}
+
+	// the newest files are kept
+	if content, _ := os.ReadFile(rotated[1]); string(content) != "record 4\n" {
+		t.Fatalf("expected the newest file to be kept, got %q", content)
+	}
+}
+
+func TestFileSinkMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "app-20200101T000000.000.log.gz")
+	unrelated := filepath.Join(dir, "app-backup.log")
+	for _, path := range []string{old, unrelated} {
+		if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		past := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(path, past, past)
+	}
+
+	sink := newTestSink(t, FileConfig{Path: filepath.Join(dir, "app.log"), MaxAge: 24 * time.Hour})
+	sink.Write([]byte("new\n"))
+	if err := sink.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	if exists(old) {
+		t.Fatal("expected the expired file to be removed")
+	}
+
+	if !exists(unrelated) {
+		t.Fatal("expected files not rotated by the sink to be kept")
+	}
+
+	if rotated := rotatedFiles(t, sink.config.Path); len(rotated) != 1 {
+		t.Fatalf("expected the new rotated file to be kept, got %v", rotated)
+	}
+}
+
+func TestFileSinkConcurrentWrites(t *testing.T) {
+	sink := newTestSink(t, FileConfig{MaxSize: 4096})
+
+	const writers, records = 16, 200
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				fmt.Fprintf(sink, "writer %02d record %03d\n", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	sink.Close()
+
+	lines := 0
+	for _, path := range append(rotatedFiles(t, sink.config.Path), sink.config.Path) {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var writer, record int
+			if _, err := fmt.Sscanf(scanner.Text(), "writer %d record %d", &writer, &record); err != nil {
+				t.Fatalf("corrupted line %q in %s", scanner.Text(), path)
+			}
+			lines++
+		}
+		file.Close()
+	}
+
+	if lines != writers*records {
+		t.Fatalf("expected %d records, got %d", writers*records, lines)
+	}
+}
+
+func TestFileSinkRecoversFromFailedRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	sink := newTestSink(t, FileConfig{Path: filepath.Join(dir, "app.log")})
+	sink.Write([]byte("before\n"))
+
+	// the new file can't be created while the directory is missing
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Rotate(); err == nil {
+		t.Fatal("expected the rotation to fail")
+	}
+
+	if _, err := sink.Write([]byte("lost\n")); err == nil {
+		t.Fatal("expected the write to fail without a file")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sink.Write([]byte("after\n")); err != nil {
+		t.Fatalf("expected writing to resume, got %v", err)
+	}
+	sink.Close()
+
+	if content, _ := os.ReadFile(sink.config.Path); string(content) != "after\n" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	if _, err := sink.Write([]byte("closed\n")); err != os.ErrClosed {
+		t.Fatalf("expected os.ErrClosed once closed, got %v", err)
+	}
+}
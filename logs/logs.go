@@ -3,7 +3,9 @@ package logs
 import (
 	"context"
 	"log/slog"
-	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
 )
 
 type Logger interface {
@@ -11,34 +13,281 @@ type Logger interface {
 	Debug(message string, args ...interface{})
 	Error(message string, args ...interface{})
 	Warn(message string, args ...interface{})
+	// With returns a logger adding the given key value pairs to every record
+	With(args ...interface{}) Logger
+	// WithGroup returns a logger nesting the attributes added afterwards
+	// under the given name
+	WithGroup(name string) Logger
 }
 
 type slogLogger struct {
 	Logger
 	ctx    context.Context
-	logger *slog.Logger
+	fields *contextFields
+	chain  *loggerChain
+	// handler derives the shared handler with the fields of ctx and the
+	// chain, derived again only when Configure replaces the shared handler
+	handler *derivedHandler
 }
 
+// loggerChain is a With or WithGroup derivation of a logger, applied
+// after the derivations of its parent
+type loggerChain struct {
+	parent *loggerChain
+	derive func(slog.Handler) slog.Handler
+	// handler applies the chain without context fields
+	handler *derivedHandler
+}
+
+func newLoggerChain(parent *loggerChain, derive func(slog.Handler) slog.Handler) *loggerChain {
+	chain := &loggerChain{parent: parent, derive: derive}
+	chain.handler = newDerivedHandler(nil, chain)
+	return chain
+}
+
+func (c *loggerChain) apply(h slog.Handler) slog.Handler {
+	if c == nil {
+		return h
+	}
+	return c.derive(c.parent.apply(h))
+}
+
+// contextFields are the fields added to a context with WithFields
+type contextFields struct {
+	fields []interface{}
+	// handler adds the fields to the shared handler
+	handler *derivedHandler
+	// chained caches the handler of the last logger chain used with the
+	// fields, like the logger of a request whose fields grow after NewContext
+	chained atomic.Pointer[chainedHandler]
+}
+
+type chainedHandler struct {
+	chain   *loggerChain
+	handler *derivedHandler
+}
+
+func newContextFields(fields []interface{}) *contextFields {
+	f := &contextFields{fields: fields}
+	f.handler = newDerivedHandler(f, nil)
+	return f
+}
+
+// derivedHandler caches a handler derived from the shared one
+type derivedHandler struct {
+	derive func(slog.Handler) slog.Handler
+	cached atomic.Pointer[generationHandler]
+}
+
+type generationHandler struct {
+	handler    slog.Handler
+	generation uint64
+}
+
+// newDerivedHandler adds the fields, before any group of the chain,
+// and then applies the chain
+func newDerivedHandler(fields *contextFields, chain *loggerChain) *derivedHandler {
+	return &derivedHandler{derive: func(h slog.Handler) slog.Handler {
+		if fields != nil && len(fields.fields) > 0 {
+			h = h.WithAttrs(toAttrs(fields.fields))
+		}
+		return chain.apply(h)
+	}}
+}
+
+// get returns the derived handler, the shared one when d is nil
+func (d *derivedHandler) get() slog.Handler {
+	h, generation := shared()
+	if d == nil {
+		return h
+	}
+
+	if cached := d.cached.Load(); cached != nil && cached.generation == generation {
+		return cached.handler
+	}
+
+	derived := d.derive(h)
+	d.cached.Store(&generationHandler{handler: derived, generation: generation})
+	return derived
+}
+
+// handlerFor returns the cached handler adding the fields and the chain
+func handlerFor(fields *contextFields, chain *loggerChain) *derivedHandler {
+	switch {
+	case fields == nil && chain == nil:
+		return nil
+	case fields == nil:
+		return chain.handler
+	case chain == nil:
+		return fields.handler
+	}
+
+	if cached := fields.chained.Load(); cached != nil && cached.chain == chain {
+		return cached.handler
+	}
+
+	handler := newDerivedHandler(fields, chain)
+	fields.chained.Store(&chainedHandler{chain: chain, handler: handler})
+	return handler
+}
+
+type loggerKey struct{}
+
+type fieldsKey struct{}
+
+// FromContext returns the logger placed in ctx with NewContext, or a logger
+// writing through the shared handler. The fields added to ctx with
+// WithFields are included in every record.
 func FromContext(ctx context.Context) Logger {
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug, // Asegúrate de establecer el nivel adecuado
+	fields := fieldsOf(ctx)
+
+	if ctx != nil {
+		switch logger := ctx.Value(loggerKey{}).(type) {
+		case *slogLogger:
+			// bound to ctx, so fields added after NewContext are included
+			if logger.fields == fields {
+				return &slogLogger{ctx: ctx, fields: fields, chain: logger.chain, handler: logger.handler}
+			}
+			return &slogLogger{ctx: ctx, fields: fields, chain: logger.chain, handler: handlerFor(fields, logger.chain)}
+		case Logger:
+			return logger
+		}
+	}
+
+	return &slogLogger{ctx: ctx, fields: fields, handler: handlerFor(fields, nil)}
+}
+
+// NewContext returns a copy of ctx carrying the logger returned by FromContext
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithFields returns a copy of ctx whose loggers add the given key value
+// pairs to every record, replacing the values of the keys already added
+func WithFields(ctx context.Context, args ...interface{}) context.Context {
+	fields := append([]interface{}(nil), Fields(ctx)...)
+
+	for i := 0; i < len(args); {
+		key, ok := args[i].(string)
+		if !ok || i+1 == len(args) {
+			// attributes and missing values are added as slog adds them
+			fields = append(fields, args[i])
+			i++
+			continue
+		}
+
+		if index := fieldIndex(fields, key); index >= 0 {
+			fields[index+1] = args[i+1]
+		} else {
+			fields = append(fields, key, args[i+1])
+		}
+		i += 2
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, newContextFields(fields))
+}
+
+// fieldIndex returns the position of the key in the fields, or -1
+func fieldIndex(fields []interface{}, key string) int {
+	for i := 0; i < len(fields); i++ {
+		if _, ok := fields[i].(string); !ok {
+			continue
+		}
+
+		if fields[i] == key && i+1 < len(fields) {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// Fields returns the key value pairs added to ctx with WithFields
+func Fields(ctx context.Context) []interface{} {
+	if fields := fieldsOf(ctx); fields != nil {
+		return fields.fields
+	}
+	return nil
+}
+
+func fieldsOf(ctx context.Context) *contextFields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(*contextFields)
+	return fields
+}
+
+func (log *slogLogger) With(args ...interface{}) Logger {
+	attrs := toAttrs(args)
+	return log.derive(func(h slog.Handler) slog.Handler {
+		return h.WithAttrs(attrs)
+	})
+}
+
+func (log *slogLogger) WithGroup(name string) Logger {
+	return log.derive(func(h slog.Handler) slog.Handler {
+		return h.WithGroup(name)
 	})
-	logger := slog.New(handler)
-	return &slogLogger{logger: logger, ctx: ctx}
+}
+
+// derive returns a logger whose handler is derived once for its fields
+func (log *slogLogger) derive(handler func(slog.Handler) slog.Handler) Logger {
+	chain := newLoggerChain(log.chain, handler)
+	return &slogLogger{
+		ctx:     log.ctx,
+		fields:  log.fields,
+		chain:   chain,
+		handler: newDerivedHandler(log.fields, chain),
+	}
 }
 
 func (log *slogLogger) Debug(message string, args ...interface{}) {
-	log.logger.Log(log.ctx, slog.LevelDebug.Level(), message, args...)
+	log.log(slog.LevelDebug, message, args...)
 }
 
 func (log *slogLogger) Error(message string, args ...interface{}) {
-	log.logger.Log(log.ctx, slog.LevelError.Level(), message, args...)
+	log.log(slog.LevelError, message, args...)
 }
 
 func (log *slogLogger) Info(message string, args ...interface{}) {
-	log.logger.Log(log.ctx, slog.LevelInfo.Level(), message, args...)
+	log.log(slog.LevelInfo, message, args...)
 }
 
 func (log *slogLogger) Warn(message string, args ...interface{}) {
-	log.logger.Log(log.ctx, slog.LevelWarn.Level(), message, args...)
+	log.log(slog.LevelWarn, message, args...)
+}
+
+func (log *slogLogger) log(level slog.Level, message string, args ...interface{}) {
+	ctx := log.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	handler := log.handler.get()
+	if !handler.Enabled(ctx, level) {
+		return
+	}
+
+	// skips runtime.Callers, log and the level method, so the source
+	// location points to the caller of the logger
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), level, message, pcs[0])
+	record.Add(args...)
+	_ = handler.Handle(ctx, record)
+}
+
+// toAttrs converts key value pairs to attributes the way slog does for records
+func toAttrs(args []interface{}) []slog.Attr {
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return attrs
 }
@@ -0,0 +1,63 @@
+package logs
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLoggerHandlerDerivedOnce(t *testing.T) {
+	captureLogs(t, Redaction{})
+
+	logger := FromContext(WithFields(context.Background(), "tenant", "acme")).With("job", "invoices").(*slogLogger)
+	logger.Info("first")
+	derived := logger.handler.cached.Load()
+	logger.Info("second")
+
+	if derived == nil || logger.handler.cached.Load() != derived {
+		t.Fatal("expected the handler to be derived once")
+	}
+}
+
+func TestRequestLoggerHandlerDerivedOnce(t *testing.T) {
+	output := captureLogs(t, Redaction{})
+
+	// like the request logger middleware, followed by fields added later
+	// in the pipeline, like the authenticated principal
+	ctx := WithFields(context.Background(), "request_id", "r1")
+	ctx = NewContext(ctx, FromContext(ctx).WithGroup("request"))
+	ctx = WithFields(ctx, "principal", "alice")
+
+	first := FromContext(ctx).(*slogLogger)
+	first.Info("first", "status", 200)
+	second := FromContext(ctx).(*slogLogger)
+	second.Info("second", "status", 200)
+
+	if first.handler != second.handler || first.handler.cached.Load() == nil {
+		t.Fatal("expected the loggers of the context to share the derived handler")
+	}
+
+	expected := `"request_id":"r1","principal":"alice","request":{"status":200}`
+	if lines := strings.Split(strings.TrimSpace(output.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], expected) {
+		t.Fatalf("expected %s in every record, got %s", expected, output)
+	}
+}
+
+func TestLoggerFollowsConfigure(t *testing.T) {
+	before := captureLogs(t, Redaction{})
+
+	logger := FromContext(WithFields(context.Background(), "tenant", "acme")).WithGroup("billing")
+	logger.Info("before", "amount", 1)
+
+	after := captureLogs(t, Redaction{})
+	logger.Info("after", "amount", 2)
+
+	if !strings.Contains(before.String(), `"msg":"before"`) || strings.Contains(before.String(), `"msg":"after"`) {
+		t.Fatalf("unexpected records before Configure: %s", before)
+	}
+
+	expected := `"msg":"after","tenant":"acme","billing":{"amount":2}`
+	if !strings.Contains(after.String(), expected) {
+		t.Fatalf("expected %s after Configure, got %s", expected, after)
+	}
+}
@@ -0,0 +1,449 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redaction lists the sensitive values removed from the records
+type Redaction struct {
+	// Headers are header names, like "Authorization", whose values are
+	// removed from http.Header attributes. Names are case insensitive.
+	Headers []string
+	// Fields are attribute, JSON and query parameter names to redact. Dot
+	// separated paths like "user.password" match from the root, "*" matching
+	// any name, while single names like "password" match at any depth.
+	Fields []string
+	// Patterns replace the matching text of string values, like card numbers
+	Patterns []*regexp.Regexp
+	// Replacement of the redacted values, "[REDACTED]" by default
+	Replacement string
+}
+
+func (r Redaction) empty() bool {
+	return len(r.Headers) == 0 && len(r.Fields) == 0 && len(r.Patterns) == 0
+}
+
+type redactor struct {
+	rules       bool
+	headers     map[string]bool
+	fields      [][]string
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+func newRedactor(rules Redaction) *redactor {
+	r := &redactor{
+		rules:       !rules.empty(),
+		headers:     make(map[string]bool, len(rules.Headers)),
+		fields:      make([][]string, 0, len(rules.Fields)),
+		patterns:    rules.Patterns,
+		replacement: rules.Replacement,
+	}
+
+	if r.replacement == "" {
+		r.replacement = "[REDACTED]"
+	}
+
+	for _, header := range rules.Headers {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	for _, field := range rules.Fields {
+		r.fields = append(r.fields, strings.Split(strings.ToLower(field), "."))
+	}
+
+	return r
+}
+
+// matches reports whether the field at path must be redacted
+func (r *redactor) matches(path []string) bool {
+	for _, field := range r.fields {
+		if len(field) == 1 {
+			if len(path) > 0 && (field[0] == "*" || strings.EqualFold(path[len(path)-1], field[0])) {
+				return true
+			}
+			continue
+		}
+
+		if len(field) != len(path) {
+			continue
+		}
+
+		matched := true
+		for i, name := range field {
+			if name != "*" && !strings.EqualFold(path[i], name) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *redactor) text(value string) string {
+	for _, pattern := range r.patterns {
+		value = pattern.ReplaceAllString(value, r.replacement)
+	}
+	return value
+}
+
+func (r *redactor) attr(path []string, attr slog.Attr) slog.Attr {
+	path = append(path[:len(path):len(path)], attr.Key)
+	if r.matches(path) {
+		return slog.String(attr.Key, r.replacement)
+	}
+
+	return slog.Attr{Key: attr.Key, Value: r.value(path, attr.Value)}
+}
+
+func (r *redactor) value(path []string, value slog.Value) slog.Value {
+	value = value.Resolve()
+
+	switch value.Kind() {
+	case slog.KindString:
+		if !r.rules {
+			return value
+		}
+		if redacted, ok := r.jsonText([]byte(value.String())); ok {
+			return slog.StringValue(redacted)
+		}
+		return slog.StringValue(r.text(value.String()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		result := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			result[i] = r.attr(path, attr)
+		}
+		return slog.GroupValue(result...)
+	case slog.KindAny:
+		return r.any(path, value)
+	}
+
+	return value
+}
+
+func (r *redactor) any(path []string, value slog.Value) slog.Value {
+	if !r.rules {
+		// only the tagged struct fields are redacted without rules
+		if group, ok := redactedStruct(value.Any(), r.replacement); ok {
+			return r.value(path, group)
+		}
+		return value
+	}
+
+	switch v := value.Any().(type) {
+	case http.Header:
+		return slog.AnyValue(r.header(v))
+	case map[string][]string:
+		return slog.AnyValue(map[string][]string(r.header(v)))
+	case json.RawMessage:
+		if redacted, ok := r.jsonText(v); ok {
+			return slog.AnyValue(json.RawMessage(redacted))
+		}
+	case []byte:
+		if redacted, ok := r.jsonText(v); ok {
+			return slog.StringValue(redacted)
+		}
+	case error:
+		return slog.StringValue(r.text(v.Error()))
+	}
+
+	if group, ok := redactedStruct(value.Any(), r.replacement); ok {
+		return r.value(path, group)
+	}
+
+	if redacted, ok := r.document(path, value.Any()); ok {
+		return slog.AnyValue(redacted)
+	}
+
+	return value
+}
+
+// document redacts maps, slices and structs walking their JSON encoding,
+// like decoded request bodies, returning false when nothing was redacted
+func (r *redactor) document(path []string, value interface{}) (json.RawMessage, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+	default:
+		return nil, false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, false
+	}
+
+	redacted := false
+	document = r.json(path, document, &redacted)
+	if !redacted {
+		return nil, false
+	}
+
+	result, err := json.Marshal(document)
+	if err != nil {
+		return nil, false
+	}
+
+	return result, true
+}
+
+func (r *redactor) header(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for key, values := range header {
+		if r.headers[http.CanonicalHeaderKey(key)] || r.matches([]string{key}) {
+			result[key] = []string{r.replacement}
+			continue
+		}
+
+		redacted := make([]string, len(values))
+		for i, value := range values {
+			redacted[i] = r.text(value)
+		}
+		result[key] = redacted
+	}
+	return result
+}
+
+// jsonText redacts the fields of a JSON object or array body
+func (r *redactor) jsonText(data []byte) (string, bool) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' && trimmed[0] != '[' {
+		return "", false
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(trimmed, &document); err != nil {
+		return "", false
+	}
+
+	result, err := json.Marshal(r.json(nil, document, new(bool)))
+	if err != nil {
+		return "", false
+	}
+
+	return string(result), true
+}
+
+// json redacts a decoded JSON document in place, setting redacted
+// when any value was replaced
+func (r *redactor) json(path []string, value interface{}, redacted *bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			itemPath := append(path[:len(path):len(path)], key)
+			if r.matches(itemPath) {
+				v[key] = r.replacement
+				*redacted = true
+				continue
+			}
+			v[key] = r.json(itemPath, item, redacted)
+		}
+	case []interface{}:
+		// array items keep the path of the array, so "items.token"
+		// matches the token of every item
+		for i, item := range v {
+			v[i] = r.json(path, item, redacted)
+		}
+	case string:
+		text := r.text(v)
+		*redacted = *redacted || text != v
+		return text
+	}
+	return value
+}
+
+// uri redacts the values of the query parameters of a request URI
+func (r *redactor) uri(uri string) string {
+	path, query, found := strings.Cut(uri, "?")
+	if !found {
+		return r.text(uri)
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return r.text(uri)
+	}
+
+	for key := range values {
+		if r.matches([]string{key}) {
+			values[key] = []string{r.replacement}
+		}
+	}
+
+	return r.text(path + "?" + values.Encode())
+}
+
+// redactedStructs caches the structs having fields tagged `log:"redact"`
+var redactedStructs sync.Map
+
+// redactedStruct converts a struct with fields tagged `log:"redact"` to a
+// group of its exported fields, replacing the tagged ones
+func redactedStruct(value interface{}, replacement string) (slog.Value, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return slog.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || !hasRedactedFields(v.Type()) {
+		return slog.Value{}, false
+	}
+
+	attrs := make([]slog.Attr, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		if field.Tag.Get("log") == "redact" {
+			attrs = append(attrs, slog.String(name, replacement))
+			continue
+		}
+
+		attrs = append(attrs, slog.Any(name, v.Field(i).Interface()))
+	}
+
+	return slog.GroupValue(attrs...), true
+}
+
+func hasRedactedFields(t reflect.Type) bool {
+	if cached, ok := redactedStructs.Load(t); ok {
+		return cached.(bool)
+	}
+
+	// only complete results are cached, so records logged concurrently
+	// never see a partial answer for a type still being walked
+	result := walkRedactedFields(t, make(map[reflect.Type]bool))
+	redactedStructs.Store(t, result)
+	return result
+}
+
+// walkRedactedFields reports whether t or its nested structs have redacted
+// fields, skipping the types already visited so recursive types terminate
+func walkRedactedFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if cached, ok := redactedStructs.Load(t); ok {
+		return cached.(bool)
+	}
+
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("log") == "redact" {
+			return true
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if field.PkgPath == "" && ft.Kind() == reflect.Struct && walkRedactedFields(ft, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactingHandler removes the sensitive values of the records
+// before passing them to the next handler
+type redactingHandler struct {
+	next     slog.Handler
+	redactor *redactor
+	groups   []string
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	result := slog.NewRecord(record.Time, record.Level, h.redactor.text(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		result.AddAttrs(h.redactor.attr(h.groups, attr))
+		return true
+	})
+	return h.next.Handle(ctx, result)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactor.attr(h.groups, attr)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redacted), redactor: h.redactor, groups: h.groups}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	groups := append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &redactingHandler{next: h.next.WithGroup(name), redactor: h.redactor, groups: groups}
+}
+
+// RedactURI removes the values of the query parameters matching the
+// configured fields and the text matching the patterns from a request URI
+func RedactURI(uri string) string {
+	current()
+	mu.RLock()
+	r := redaction
+	mu.RUnlock()
+
+	if r == nil {
+		return uri
+	}
+	return r.uri(uri)
+}
+
+// RedactHeaders returns a copy of the headers without the values
+// of the configured header names
+func RedactHeaders(header http.Header) http.Header {
+	current()
+	mu.RLock()
+	r := redaction
+	mu.RUnlock()
+
+	if r == nil {
+		return header
+	}
+	return r.header(header)
+}
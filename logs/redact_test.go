@@ -0,0 +1,177 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type credentials struct {
+	User     string
+	Password string `log:"redact"`
+}
+
+type account struct {
+	Parent      *account
+	Credentials credentials
+}
+
+type plain struct {
+	Name string
+	Next *plain
+}
+
+func TestHasRedactedFieldsRecursive(t *testing.T) {
+	if !hasRedactedFields(reflect.TypeOf(account{})) {
+		t.Fatal("expected account to have redacted fields")
+	}
+
+	if hasRedactedFields(reflect.TypeOf(plain{})) {
+		t.Fatal("expected plain to have no redacted fields")
+	}
+}
+
+func TestHasRedactedFieldsConcurrent(t *testing.T) {
+	type nested struct {
+		Self  *nested
+		Login credentials
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if !hasRedactedFields(reflect.TypeOf(nested{})) {
+				t.Error("a concurrent first use saw the struct without redacted fields")
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
+
+// captureLogs configures JSON records written to the returned buffer,
+// restoring the default configuration when the test ends
+func captureLogs(t *testing.T, redaction Redaction) *bytes.Buffer {
+	t.Helper()
+
+	var output bytes.Buffer
+	if err := Configure(Config{Format: JSONFormat, Output: &output, Redaction: redaction}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		Configure(Config{Level: LevelDebug})
+	})
+
+	return &output
+}
+
+func TestRedactTaggedFieldsByDefault(t *testing.T) {
+	output := captureLogs(t, Redaction{})
+
+	FromContext(context.Background()).Info("login", "creds", credentials{User: "ana", Password: "hunter2"})
+
+	if strings.Contains(output.String(), "hunter2") {
+		t.Fatalf("expected the tagged field to be redacted, got %s", output)
+	}
+
+	if !strings.Contains(output.String(), `"creds":{"User":"ana","Password":"[REDACTED]"}`) {
+		t.Fatalf("expected the other fields to be kept, got %s", output)
+	}
+}
+
+func TestRedactOutput(t *testing.T) {
+	type body struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected string
+	}{
+		{
+			name:     "attribute",
+			args:     []interface{}{"password", "x"},
+			expected: `"password":"[REDACTED]"`,
+		},
+		{
+			name:     "map",
+			args:     []interface{}{"payload", map[string]interface{}{"user": "ana", "password": "x"}},
+			expected: `"payload":{"password":"[REDACTED]","user":"ana"}`,
+		},
+		{
+			name:     "string map",
+			args:     []interface{}{"payload", map[string]string{"password": "x"}},
+			expected: `"payload":{"password":"[REDACTED]"}`,
+		},
+		{
+			name:     "nested map",
+			args:     []interface{}{"payload", map[string]interface{}{"account": map[string]interface{}{"password": "x"}}},
+			expected: `"payload":{"account":{"password":"[REDACTED]"}}`,
+		},
+		{
+			name:     "struct",
+			args:     []interface{}{"payload", body{User: "ana", Password: "x"}},
+			expected: `"payload":{"password":"[REDACTED]","user":"ana"}`,
+		},
+		{
+			name:     "json body",
+			args:     []interface{}{"body", []byte(`{"items":[{"password":"x"}]}`)},
+			expected: `"body":"{\"items\":[{\"password\":\"[REDACTED]\"}]}"`,
+		},
+		{
+			name:     "header",
+			args:     []interface{}{"headers", http.Header{"Authorization": {"Bearer x"}}},
+			expected: `"headers":{"Authorization":["[REDACTED]"]}`,
+		},
+		{
+			name:     "pattern",
+			args:     []interface{}{"card", "paid with 4111-1111-1111-1111"},
+			expected: `"card":"paid with [REDACTED]"`,
+		},
+		{
+			name:     "unmatched map",
+			args:     []interface{}{"payload", map[string]interface{}{"user": "ana"}},
+			expected: `"payload":{"user":"ana"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := captureLogs(t, Redaction{
+				Headers:  []string{"Authorization"},
+				Fields:   []string{"password"},
+				Patterns: []*regexp.Regexp{regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`)},
+			})
+
+			FromContext(context.Background()).Info("request", test.args...)
+
+			if !strings.Contains(output.String(), test.expected) {
+				t.Fatalf("expected %s in %s", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestRedactDottedPath(t *testing.T) {
+	output := captureLogs(t, Redaction{Fields: []string{"payload.token"}})
+
+	FromContext(context.Background()).Info("request",
+		"payload", map[string]interface{}{"token": "x", "inner": map[string]interface{}{"token": "y"}})
+
+	expected := `"payload":{"inner":{"token":"y"},"token":"[REDACTED]"}`
+	if !strings.Contains(output.String(), expected) {
+		t.Fatalf("expected %s in %s", expected, output)
+	}
+}
@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// DefaultBuckets are the histogram buckets used when none are given,
+// suited to request latencies in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the Prometheus text format
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+	// collectors run before the metrics are written, to update
+	// the metrics read from elsewhere like the Go runtime
+	collectors []func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+var defaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.RegisterRuntimeMetrics()
+	return r
+}
+
+// Default returns the registry used by the package level functions,
+// which includes the Go runtime metrics
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// family is a metric with every series of its label values
+type family struct {
+	name    string
+	help    string
+	mType   metricType
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// histograms count the observations of every bucket
+	counts []uint64
+	count  uint64
+}
+
+// OnCollect registers a function run every time the metrics are written
+func (r *Registry) OnCollect(collect func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collect)
+}
+
+// register returns the family with the given name, creating it when needed.
+// Registering a name with another type or labels panics, as it is a
+// programming error.
+func (r *Registry) register(name, help string, mType metricType, buckets []float64, labels []string) *family {
+	if !validName(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+
+	for _, label := range labels {
+		if !validName(label) || strings.HasPrefix(label, "__") || label == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q", label))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.mType != mType || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("metrics: %s already registered as a %s with labels %v", name, f.mType, f.labels))
+		}
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		mType:   mType,
+		labels:  append([]string(nil), labels...),
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// with returns the series of the label values, the lock must be held
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.mType == histogramType {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// snapshot copies the series sorted by label values
+func (f *family) snapshot() []series {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make([]series, 0, len(f.series))
+	for _, s := range f.series {
+		copied := *s
+		copied.counts = append([]uint64(nil), s.counts...)
+		result = append(result, copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.Join(result[i].labelValues, "\xff") < strings.Join(result[j].labelValues, "\xff")
+	})
+
+	return result
+}
+
+// Counter is a value that only goes up, like the number of requests served
+type Counter struct {
+	family *family
+}
+
+// Counter returns the counter with the given name, registering it on first use
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{family: r.register(name, help, counterType, nil, labels)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter, panicking with negative values
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+
+	c.family.mu.Lock()
+	defer c.family.mu.Unlock()
+	c.family.with(labelValues).value += value
+}
+
+// set replaces the value of counters read from elsewhere, like the runtime
+func (c *Counter) set(value float64, labelValues ...string) {
+	c.family.mu.Lock()
+	defer c.family.mu.Unlock()
+	c.family.with(labelValues).value = value
+}
+
+// Gauge is a value that goes up and down, like the requests in flight
+type Gauge struct {
+	family *family
+}
+
+// Gauge returns the gauge with the given name, registering it on first use
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{family: r.register(name, help, gaugeType, nil, labels)}
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	g.family.with(labelValues).value = value
+}
+
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	g.family.with(labelValues).value += value
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Histogram counts observations, like request latencies, in buckets
+type Histogram struct {
+	family *family
+}
+
+// Histogram returns the histogram with the given name, registering it on
+// first use. Buckets are the upper bounds of the buckets, DefaultBuckets
+// when nil.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	if len(buckets) > 0 && math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+
+	return &Histogram{family: r.register(name, help, histogramType, buckets, labels)}
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.family.mu.Lock()
+	defer h.family.mu.Unlock()
+
+	s := h.family.with(labelValues)
+	for i, bound := range h.family.buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// NewCounter registers a counter in the default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	return defaultRegistry.Counter(name, help, labels...)
+}
+
+// NewGauge registers a gauge in the default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return defaultRegistry.Gauge(name, help, labels...)
+}
+
+// NewHistogram registers a histogram in the default registry
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return defaultRegistry.Histogram(name, help, buckets, labels...)
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, char := range name {
+		switch {
+		case char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z':
+		case i > 0 && char >= '0' && char <= '9':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
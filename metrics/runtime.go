@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+var startTime = time.Now()
+
+// RegisterRuntimeMetrics adds the Go runtime metrics to the registry,
+// read from the runtime every time the metrics are written
+func (r *Registry) RegisterRuntimeMetrics() {
+	info := r.Gauge("go_info", "Information about the Go environment.", "version")
+	goroutines := r.Gauge("go_goroutines", "Number of goroutines that currently exist.")
+	threads := r.Gauge("go_threads", "Number of OS threads created.")
+	alloc := r.Gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.")
+	allocTotal := r.Counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.")
+	sys := r.Gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.")
+	heapInuse := r.Gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.")
+	heapObjects := r.Gauge("go_memstats_heap_objects", "Number of allocated objects.")
+	gcCycles := r.Counter("go_gc_cycles_total", "Number of completed GC cycles.")
+	gcPause := r.Counter("go_gc_pause_seconds_total", "Total time spent in GC stop-the-world pauses.")
+	lastGC := r.Gauge("go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.")
+	start := r.Gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.")
+
+	info.Set(1, runtime.Version())
+	start.Set(float64(startTime.UnixNano()) / 1e9)
+
+	r.OnCollect(func() {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+
+		threadCount, _ := runtime.ThreadCreateProfile(nil)
+
+		goroutines.Set(float64(runtime.NumGoroutine()))
+		threads.Set(float64(threadCount))
+		alloc.Set(float64(stats.Alloc))
+		allocTotal.set(float64(stats.TotalAlloc))
+		sys.Set(float64(stats.Sys))
+		heapInuse.Set(float64(stats.HeapInuse))
+		heapObjects.Set(float64(stats.HeapObjects))
+		gcCycles.set(float64(stats.NumGC))
+		gcPause.set(float64(stats.PauseTotalNs) / 1e9)
+		lastGC.Set(float64(stats.LastGC) / 1e9)
+	})
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteTo writes every metric of the registry in the Prometheus
+// text exposition format, sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	collectors := append([]func(){}, r.collectors...)
+	r.mu.RUnlock()
+
+	for _, collect := range collectors {
+		collect()
+	}
+
+	r.mu.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	counter := &countingWriter{w: w}
+	b := bufio.NewWriter(counter)
+	for _, f := range families {
+		f.write(b)
+	}
+
+	err := b.Flush()
+	return counter.n, err
+}
+
+func (f *family) write(b *bufio.Writer) {
+	b.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	b.WriteString("# TYPE " + f.name + " " + string(f.mType) + "\n")
+
+	for _, s := range f.snapshot() {
+		if f.mType != histogramType {
+			writeSample(b, f.name, f.labels, s.labelValues, "", s.value)
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			writeSample(b, f.name+"_bucket", f.labels, s.labelValues, formatFloat(bound), float64(cumulative))
+		}
+		writeSample(b, f.name+"_bucket", f.labels, s.labelValues, "+Inf", float64(s.count))
+		writeSample(b, f.name+"_sum", f.labels, s.labelValues, "", s.value)
+		writeSample(b, f.name+"_count", f.labels, s.labelValues, "", float64(s.count))
+	}
+}
+
+func writeSample(b *bufio.Writer, name string, labels, values []string, le string, value float64) {
+	b.WriteString(name)
+
+	if len(labels) > 0 || le != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+
+		if le != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(`le="` + le + `"`)
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,176 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceID identifies a trace across every service it goes through
+type TraceID [16]byte
+
+// SpanID identifies a span inside a trace
+type SpanID [8]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+const sampledFlag byte = 0x01
+
+// SpanContext is the part of a span propagated to other services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote is set for span contexts received from another service
+	Remote bool
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+func (c SpanContext) Sampled() bool {
+	return c.Flags&sampledFlag != 0
+}
+
+// Traceparent formats the span context as a W3C traceparent header
+func (c SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", c.TraceID, c.SpanID, c.Flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isHex(parts[0]) {
+		return SpanContext{}, false
+	}
+
+	// version 00 has exactly four fields, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	result := SpanContext{Remote: true}
+	if len(parts[1]) != 32 || !isHex(parts[1]) || len(parts[2]) != 16 || !isHex(parts[2]) || len(parts[3]) != 2 || !isHex(parts[3]) {
+		return SpanContext{}, false
+	}
+
+	hex.Decode(result.TraceID[:], []byte(parts[1]))
+	hex.Decode(result.SpanID[:], []byte(parts[2]))
+
+	var flags [1]byte
+	hex.Decode(flags[:], []byte(parts[3]))
+	result.Flags = flags[0]
+
+	if !result.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return result, true
+}
+
+// isHex reports whether value only has lowercase hexadecimal characters
+func isHex(value string) bool {
+	for _, char := range value {
+		if !(char >= '0' && char <= '9' || char >= 'a' && char <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract reads the span context of the traceparent and tracestate headers
+func Extract(header http.Header) (SpanContext, bool) {
+	result, ok := ParseTraceparent(header.Get("traceparent"))
+	if !ok {
+		return SpanContext{}, false
+	}
+
+	state := strings.Join(header.Values("tracestate"), ",")
+	if len(state) <= 512 {
+		result.TraceState = state
+	}
+
+	return result, true
+}
+
+// Inject writes the span context of the span in ctx to the traceparent
+// and tracestate headers of an outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	header.Set("traceparent", span.context.Traceparent())
+	if span.context.TraceState != "" {
+		header.Set("tracestate", span.context.TraceState)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext returns the active span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan returns a copy of ctx where span is the active span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemote returns a copy of ctx whose next span continues the
+// trace received from another service
+func ContextWithRemote(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+func remoteFromContext(ctx context.Context) (SpanContext, bool) {
+	parent, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return parent, ok
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporterFlushOnShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var requests []otlpTraces
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected export request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+
+		var payload otlpTraces
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+
+		mu.Lock()
+		requests = append(requests, payload)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "orders")
+	tracer := NewTracer(Config{Exporter: exporter, Interval: time.Hour})
+
+	ctx, parent := tracer.Start(context.Background(), "GET /orders", KindServer)
+	_, child := tracer.Start(ctx, "load orders", KindInternal)
+	child.SetAttribute("orders.count", 3)
+	child.SetStatus(StatusError, "timeout")
+	child.End()
+	parent.End()
+
+	// the interval never elapses, so the spans are only exported by Shutdown
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(requests) != 1 {
+		t.Fatalf("expected a single export request, got %d", len(requests))
+	}
+
+	resource := requests[0].ResourceSpans[0]
+	if name := resource.Resource.Attributes[0]; name.Key != "service.name" || *name.Value.StringValue != "orders" {
+		t.Fatalf("unexpected resource attributes %+v", resource.Resource.Attributes)
+	}
+
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	exportedChild, exportedParent := spans[0], spans[1]
+	if exportedChild.TraceID != parent.TraceID().String() || exportedParent.TraceID != parent.TraceID().String() {
+		t.Fatal("expected both spans in the parent trace")
+	}
+
+	if exportedChild.ParentSpanID != parent.SpanID().String() || exportedParent.ParentSpanID != "" {
+		t.Fatalf("unexpected parent span IDs %q and %q", exportedChild.ParentSpanID, exportedParent.ParentSpanID)
+	}
+
+	if exportedChild.Name != "load orders" || exportedChild.Kind != int(KindInternal) || exportedParent.Kind != int(KindServer) {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+
+	if attribute := exportedChild.Attributes[0]; attribute.Key != "orders.count" || *attribute.Value.IntValue != "3" {
+		t.Fatalf("unexpected attributes %+v", exportedChild.Attributes)
+	}
+
+	if exportedChild.Status.Code != int(StatusError) || exportedChild.Status.Message != "timeout" {
+		t.Fatalf("unexpected status %+v", exportedChild.Status)
+	}
+}
+
+type blockingExporter struct {
+	InMemoryExporter
+	release chan struct{}
+}
+
+func (e *blockingExporter) Export(ctx context.Context, spans []SpanData) error {
+	<-e.release
+	return e.InMemoryExporter.Export(ctx, spans)
+}
+
+func TestTracerDropsSpansWhenQueueIsFull(t *testing.T) {
+	exporter := &blockingExporter{release: make(chan struct{})}
+	tracer := NewTracer(Config{Exporter: exporter, BatchSize: 1, QueueSize: 2, Interval: time.Hour})
+
+	// ending spans never blocks, even with the exporter stalled
+	for i := 0; i < 100; i++ {
+		_, span := tracer.Start(context.Background(), "work", KindInternal)
+		span.End()
+	}
+
+	close(exporter.release)
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// one batch being exported when the queue filled, plus the queued ones
+	if exported := len(exporter.Spans()); exported < 1 || exported > 3 {
+		t.Fatalf("expected the spans beyond the queue to be dropped, %d were exported", exported)
+	}
+
+	_, span := tracer.Start(context.Background(), "late", KindInternal)
+	span.End()
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,404 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ramoncl001/go-comet/logs"
+)
+
+type SpanKind int
+
+const (
+	KindInternal SpanKind = iota + 1
+	KindServer
+	KindClient
+)
+
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// SpanData is a finished span, as received by the exporters
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	TraceState    string
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span is an operation of a trace, like serving a request
+type Span struct {
+	tracer  *Tracer
+	context SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Context returns the span context propagated to other services
+func (s *Span) Context() SpanContext {
+	return s.context
+}
+
+func (s *Span) TraceID() TraceID {
+	return s.context.TraceID
+}
+
+func (s *Span) SpanID() SpanID {
+	return s.context.SpanID
+}
+
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	s.data.Name = name
+}
+
+// SetAttribute records a string, bool, integer or float attribute.
+// Spans are not modified once ended.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	s.data.StatusCode = code
+	s.data.StatusMessage = message
+}
+
+// RecordError marks the span as failed with the error message
+func (s *Span) RecordError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+// End finishes the span, exporting it when sampled
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.context.Sampled() {
+		s.tracer.enqueue(data)
+	}
+}
+
+// Config configures a Tracer
+type Config struct {
+	// Exporter receiving the finished spans, none are exported when nil
+	Exporter Exporter
+	// SampleRate is the fraction of new traces sampled, between 0 and 1.
+	// Every trace is sampled when zero. Traces continued from another
+	// service follow the decision of their parent.
+	SampleRate float64
+	// BatchSize is the number of spans exported together, 512 by default
+	BatchSize int
+	// Interval between exports of incomplete batches, 5 seconds by default
+	Interval time.Duration
+	// QueueSize is the number of full batches waiting to be exported,
+	// 8 by default. Spans ended while the queue is full are dropped.
+	QueueSize int
+}
+
+// Tracer creates spans and exports them in batches, from a single
+// goroutine fed by a bounded queue
+type Tracer struct {
+	config Config
+
+	mu      sync.Mutex
+	pending []SpanData
+	dropped int
+	stopped bool
+
+	queue   chan []SpanData
+	flushes chan flushRequest
+	stop    chan context.Context
+	done    chan error
+	exited  chan struct{}
+}
+
+type flushRequest struct {
+	ctx    context.Context
+	result chan error
+}
+
+func NewTracer(config Config) *Tracer {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 512
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = 8
+	}
+
+	t := &Tracer{
+		config:  config,
+		queue:   make(chan []SpanData, config.QueueSize),
+		flushes: make(chan flushRequest),
+		stop:    make(chan context.Context),
+		done:    make(chan error, 1),
+		exited:  make(chan struct{}),
+	}
+
+	if config.Exporter != nil {
+		go t.loop()
+	} else {
+		close(t.exited)
+	}
+
+	return t
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer = NewTracer(Config{})
+)
+
+// Default returns the tracer used by Start, which exports
+// nothing until replaced with SetDefault
+func Default() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracer
+}
+
+func SetDefault(tracer *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTracer = tracer
+}
+
+// Start starts a span with the tracer of the active span of ctx,
+// or the default tracer when there is none
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.tracer.Start(ctx, name, kind)
+	}
+	return Default().Start(ctx, name, kind)
+}
+
+// Start starts a span, child of the active span of ctx or of the remote
+// parent added with ContextWithRemote, or the root of a new trace
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var parent SpanContext
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.context
+	} else if remote, ok := remoteFromContext(ctx); ok {
+		parent = remote
+	}
+
+	spanContext := SpanContext{
+		TraceID:    parent.TraceID,
+		SpanID:     newSpanID(),
+		Flags:      parent.Flags,
+		TraceState: parent.TraceState,
+	}
+
+	if !parent.IsValid() {
+		spanContext.TraceID = newTraceID()
+		spanContext.Flags = 0
+		if t.config.SampleRate <= 0 || rand.Float64() < t.config.SampleRate {
+			spanContext.Flags = sampledFlag
+		}
+	}
+
+	span := &Span{
+		tracer:  t,
+		context: spanContext,
+		data: SpanData{
+			Name:         name,
+			Kind:         kind,
+			TraceID:      spanContext.TraceID,
+			SpanID:       spanContext.SpanID,
+			ParentSpanID: parent.SpanID,
+			TraceState:   spanContext.TraceState,
+			Start:        time.Now(),
+			Attributes:   make(map[string]interface{}),
+		},
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	if t.config.Exporter == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+
+	t.pending = append(t.pending, data)
+	if len(t.pending) < t.config.BatchSize {
+		return
+	}
+
+	// spans are dropped rather than blocking the request that ended them
+	select {
+	case t.queue <- t.pending:
+	default:
+		t.dropped += len(t.pending)
+	}
+	t.pending = nil
+}
+
+// loop is the only goroutine calling the exporter
+func (t *Tracer) loop() {
+	defer close(t.exited)
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch := <-t.queue:
+			t.export(context.Background(), batch)
+		case <-ticker.C:
+			if err := t.exportPending(context.Background()); err != nil {
+				logs.FromContext(context.Background()).Error("unable to export spans", "error", err)
+			}
+		case request := <-t.flushes:
+			request.result <- t.exportPending(request.ctx)
+		case ctx := <-t.stop:
+			t.done <- t.exportPending(ctx)
+			return
+		}
+	}
+}
+
+// exportPending exports the queued batches and the incomplete one
+func (t *Tracer) exportPending(ctx context.Context) error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = nil
+	dropped := t.dropped
+	t.dropped = 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		logs.FromContext(ctx).Warn("spans dropped, the export queue is full", "spans", dropped)
+	}
+
+	var errs []error
+	for queued := len(t.queue); queued > 0; queued-- {
+		if err := t.config.Exporter.Export(ctx, <-t.queue); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := t.config.Exporter.Export(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *Tracer) export(ctx context.Context, batch []SpanData) {
+	if err := t.config.Exporter.Export(ctx, batch); err != nil {
+		logs.FromContext(ctx).Error("unable to export spans", "spans", len(batch), "error", err)
+	}
+}
+
+// Flush exports the pending spans
+func (t *Tracer) Flush(ctx context.Context) error {
+	request := flushRequest{ctx: ctx, result: make(chan error, 1)}
+
+	select {
+	case t.flushes <- request:
+	case <-t.exited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the pending spans and shuts the exporter down.
+// Spans ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return nil
+	}
+	t.stopped = true
+	t.mu.Unlock()
+
+	if t.config.Exporter == nil {
+		return nil
+	}
+
+	// spans are no longer accepted, so the loop exports the last ones
+	select {
+	case t.stop <- ctx:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var err error
+	select {
+	case err = <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return errors.Join(err, t.config.Exporter.Shutdown(ctx))
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+// Transport is an http.RoundTripper creating a client span for every
+// outgoing request and propagating it in the traceparent header
+//
+//	client := &http.Client{Transport: &tracing.Transport{}}
+type Transport struct {
+	// Base performs the requests, http.DefaultTransport when nil
+	Base http.RoundTripper
+	// Tracer creating the spans, the default tracer when nil
+	Tracer *Tracer
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if SpanFromContext(req.Context()) == nil {
+		return t.base().RoundTrip(req)
+	}
+
+	tracer := t.Tracer
+	if tracer == nil {
+		tracer = Default()
+	}
+
+	ctx, span := tracer.Start(req.Context(), req.Method, KindClient)
+	defer span.End()
+
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("url.full", req.URL.Redacted())
+
+	// requests must not be modified by round trippers
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	res, err := t.base().RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.response.status_code", res.StatusCode)
+	if res.StatusCode >= 400 {
+		span.SetStatus(StatusError, res.Status)
+	}
+
+	return res, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}